@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// accumulatePasses refines the last frame progressively: it re-renders the
+// whole image up to Settings.MaxPasses-1 more times, each pass offset by
+// its own sub-pixel jitter, and averages every pass so far into a float32
+// buffer that is converted back to bytes for display after each one. The
+// frame just rendered is the first pass, and settings is the Clone it was
+// rendered from, which nothing else writes to. It gives up as soon as a
+// newer render starts or StopPasses is called.
+func (mi *MandelbrotImage) accumulatePasses(kernel Kernel, settings *Settings, generation int64) {
+	atomic.StoreInt32(&mi.passing, 1)
+	defer atomic.StoreInt32(&mi.passing, 0)
+
+	width := int(mi.Width)
+	bounds := settings.renderBounds()
+	accum := make([]float32, width*int(mi.Height)*3)
+
+	mi.mu.Lock()
+	for idx, iters := range mi.Iterations {
+		red, green, blue := pixelColor(nil, iters, mi.Smooth[idx], settings)
+		accum[idx*3] = float32(red)
+		accum[idx*3+1] = float32(green)
+		accum[idx*3+2] = float32(blue)
+	}
+	mi.mu.Unlock()
+
+	var pass int64
+	for pass = 1; pass < settings.MaxPasses; pass++ {
+		jittered := settings.Clone()
+		jittered.JitterX, jittered.JitterY = frameJitter(int(pass))
+
+		rows := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < mi.workers(); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for y := range rows {
+					for x := bounds.Min.X; x < bounds.Max.X; x++ {
+						idx := (y*width + x) * 3
+						red, green, blue := sampleColor(kernel, float64(x), float64(y), &jittered)
+						accum[idx] += float32(red)
+						accum[idx+1] += float32(green)
+						accum[idx+2] += float32(blue)
+					}
+				}
+			}()
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y && !mi.stale(generation); y++ {
+			rows <- y
+		}
+		close(rows)
+		wg.Wait()
+
+		// pass+1 passes are in the buffer now
+		scale := 1 / float32(pass+1)
+		mi.mu.Lock()
+		if mi.stale(generation) {
+			mi.mu.Unlock()
+			return
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				idx := y*width + x
+				px, py := float64(x), float64(y)
+				pt := pixelPoint(px, py,
+					float64(accum[idx*3]*scale), float64(accum[idx*3+1]*scale), float64(accum[idx*3+2]*scale),
+					mi.Iterations[idx], settings)
+				pt.Smooth = mi.Smooth[idx]
+				mi.drawPoint(pt)
+			}
+		}
+		mi.mu.Unlock()
+	}
+	log.WithField("passes", settings.MaxPasses).Debug("accumulated render passes")
+}
+
+// StopPasses ends progressive refinement early, keeping the passes
+// accumulated so far on screen.
+func (mi *MandelbrotImage) StopPasses() {
+	if atomic.LoadInt32(&mi.passing) == 1 {
+		atomic.AddInt64(&mi.generation, 1)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestPassesAfterViewChange moves the view while passes are accumulating,
+// as the main loop does; run with -race. The new frame must come out as
+// if the passes had never run.
+func TestPassesAfterViewChange(t *testing.T) {
+	settings := testSettings(96, 96)
+	settings.MaxPasses = 4
+	mi := startImage(&settings)
+	mi.ForceRender()
+
+	for i := 0; i < 5; i++ {
+		settings.ZoomAt(70, 30, wheelZoomFactor)
+	}
+	settings.MaxPasses = 1
+	mi.ForceRender()
+	finish(mi)
+
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// idleFrameDelay is how long the main loop waits between frames, and
+// animationFrameDelay how long while something is animating, so that an
+// animation runs over many frames rather than finishing in one or two.
+const (
+	idleFrameDelay      = 500 * time.Millisecond
+	animationFrameDelay = 16 * time.Millisecond
+)
+
+// savedNoticeDuration is how long the notice of a finished save stays up.
+const savedNoticeDuration = 2 * time.Second
+
+// iterationAnimation re-renders the current view with MaxIterations = 1,
+// 2, 3, ... up to the count it started from, showing how the set emerges
+// as the iteration limit grows. It climbs at Rate iterations per second
+// of wall-clock time, skipping counts when frames are slow.
+type iterationAnimation struct {
+	Active bool
+	Rate   float64
+	target int64
+	grown  float64
+}
+
+func (a *iterationAnimation) Start(settings *Settings) {
+	a.Active = true
+	a.target = settings.MaxIterations
+	// the first Step moves this on to the first frame, at 1 iteration
+	settings.MaxIterations = 0
+	a.grown = 0
+}
+
+// Stop ends the animation early, restoring the original iteration count.
+func (a *iterationAnimation) Stop(settings *Settings) {
+	a.Active = false
+	settings.MaxIterations = a.target
+}
+
+func (a *iterationAnimation) Toggle(settings *Settings) {
+	if a.Active {
+		a.Stop(settings)
+	} else {
+		a.Start(settings)
+	}
+}
+
+// Step advances the animation by dt and reports whether it needs
+// rendering.
+func (a *iterationAnimation) Step(settings *Settings, dt time.Duration) bool {
+	if !a.Active {
+		return false
+	}
+	if settings.MaxIterations >= a.target {
+		a.Active = false
+		return false
+	}
+
+	a.grown += a.Rate * dt.Seconds()
+	n := int64(a.grown)
+	if n < 1 {
+		n = 1
+	}
+	if n > a.target {
+		n = a.target
+	}
+	if n == settings.MaxIterations {
+		return false
+	}
+	settings.MaxIterations = n
+	return true
+}
+
+// startupRamp paints the first frames at a growing fraction of
+// MaxIterations, so something appears at once instead of after a full
+// render. It gives up if anything else changes MaxIterations meanwhile.
+// Unlike the other animations it counts frames, not time: each step is
+// there to get a render on screen.
+type startupRamp struct {
+	frames int64
+	frame  int64
+	target int64
+	last   int64
+}
+
+// newStartupRamp sets settings up for the first of frames frames; with
+// frames below 2 there is no ramp.
+func newStartupRamp(settings *Settings, frames int64) *startupRamp {
+	r := &startupRamp{frames: frames, target: settings.MaxIterations}
+	if frames > 1 {
+		r.frame = 1
+		r.apply(settings)
+	}
+	return r
+}
+
+func (r *startupRamp) apply(settings *Settings) {
+	n := r.target * r.frame / r.frames
+	if n < 1 {
+		n = 1
+	}
+	settings.MaxIterations = n
+	r.last = n
+}
+
+// Step advances to the next frame and reports whether it needs rendering.
+func (r *startupRamp) Step(settings *Settings) bool {
+	if r.frame == 0 || r.frame >= r.frames {
+		return false
+	}
+	if settings.MaxIterations != r.last {
+		r.frame = r.frames
+		return false
+	}
+
+	r.frame++
+	r.apply(settings)
+	return true
+}
+
+// zoomAnimation eases the view from one span to the next over a set
+// duration about a fixed centre, instead of jumping straight there.
+type zoomAnimation struct {
+	Active   bool
+	duration time.Duration
+	elapsed  time.Duration
+	re       float64
+	im       float64
+	from     float64
+	to       float64
+}
+
+// Zoom is Settings.Zoom spread over duration; with no duration it zooms at
+// once. A zoom during the animation continues from where the last one was
+// heading. It reports whether the view will change.
+func (a *zoomAnimation) Zoom(settings *Settings, in bool, duration time.Duration) bool {
+	if duration <= 0 {
+		return settings.Zoom(in)
+	}
+
+	target := settings.Clone()
+	if a.Active {
+		target.CenterOn(a.re, a.im, a.to)
+	}
+	if !target.Zoom(in) {
+		return false
+	}
+
+	a.re, a.im = target.ViewCenter()
+	a.from = settings.Max - settings.Min
+	a.to = target.Max - target.Min
+	a.duration = duration
+	a.elapsed = 0
+	a.Active = true
+	return true
+}
+
+// Step advances the animation by dt and reports whether it needs
+// rendering.
+func (a *zoomAnimation) Step(settings *Settings, dt time.Duration) bool {
+	if !a.Active {
+		return false
+	}
+
+	a.elapsed += dt
+	t := math.Min(1, float64(a.elapsed)/float64(a.duration))
+	settings.CenterOn(a.re, a.im, logLerp(a.from, a.to, easeOut(t)))
+	if t >= 1 {
+		a.Active = false
+	}
+	return true
+}
+
+// autoZoom flies the view to a target centre and span, Rate times closer
+// in span every second, then stops. The centre moves in step with the
+// span's progress on a log scale, and MaxIterations ramps from the
+// starting count to the target's.
+type autoZoom struct {
+	Active bool
+	Rate   float64
+
+	hasTarget        bool
+	targetRe         float64
+	targetIm         float64
+	targetSpan       float64
+	targetIterations int64
+
+	fromRe         float64
+	fromIm         float64
+	fromSpan       float64
+	fromIterations int64
+	progress       float64
+}
+
+// SetTarget makes the current view, iteration count included, the
+// target.
+func (a *autoZoom) SetTarget(settings *Settings) {
+	re, im := settings.ViewCenter()
+	a.SetTargetAt(re, im, settings.Max-settings.Min, settings.MaxIterations)
+}
+
+// SetTargetAt targets the view centred on (re, im) with the given span. An
+// iterations of 0 has Toggle work the target's count out from the start
+// view, adding Settings.IterationsPerZoomStep per ZoomFactor of zoom.
+func (a *autoZoom) SetTargetAt(re, im, span float64, iterations int64) {
+	a.hasTarget = true
+	a.targetRe, a.targetIm, a.targetSpan = re, im, span
+	a.targetIterations = iterations
+}
+
+// Toggle starts flying from the current view to the target, or stops, and
+// reports whether it started.
+func (a *autoZoom) Toggle(settings *Settings) bool {
+	if a.Active || !a.hasTarget {
+		a.Active = false
+		return false
+	}
+
+	a.fromRe, a.fromIm = settings.ViewCenter()
+	a.fromSpan = settings.Max - settings.Min
+	a.fromIterations = settings.MaxIterations
+	a.progress = 0
+	if a.targetSpan <= 0 || a.fromSpan <= 0 || a.Rate <= 1 {
+		return false
+	}
+	a.Active = true
+	return true
+}
+
+// iterationsAt is the ramped iteration count a fraction t of the way.
+func (a *autoZoom) iterationsAt(settings *Settings, t float64) int64 {
+	to := float64(a.targetIterations)
+	if a.targetIterations <= 0 {
+		to = float64(a.fromIterations)
+		if settings.ZoomFactor > 1 {
+			steps := math.Log(a.fromSpan/a.targetSpan) / math.Log(settings.ZoomFactor)
+			to += float64(settings.IterationsPerZoomStep) * steps
+		}
+	}
+	n := int64(math.Round(float64(a.fromIterations) + (to-float64(a.fromIterations))*t))
+	if n < 1 {
+		n = 1
+	}
+	if settings.IterationLimit > 0 && n > settings.IterationLimit {
+		n = settings.IterationLimit
+	}
+	return n
+}
+
+// Step advances the flight by dt and reports whether it needs rendering.
+func (a *autoZoom) Step(settings *Settings, dt time.Duration) bool {
+	if !a.Active {
+		return false
+	}
+
+	distance := math.Abs(math.Log(a.fromSpan / a.targetSpan))
+	a.progress += math.Log(a.Rate) * dt.Seconds()
+	t := 1.0
+	if distance > 0 {
+		t = math.Min(1, a.progress/distance)
+	}
+
+	settings.CenterOn(
+		a.fromRe+(a.targetRe-a.fromRe)*t,
+		a.fromIm+(a.targetIm-a.fromIm)*t,
+		logLerp(a.fromSpan, a.targetSpan, t))
+	settings.MaxIterations = a.iterationsAt(settings, t)
+	if t >= 1 {
+		a.Active = false
+	}
+	return true
+}
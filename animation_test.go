@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// frameSteps are the frame lengths to split the same stretch of time into:
+// the idle tick, a 60Hz tick, and an uneven mix.
+var frameSteps = [][]time.Duration{
+	{idleFrameDelay},
+	{time.Second / 64},
+	{time.Second / 64, 3 * time.Second / 64, time.Second / 16, time.Second / 8},
+}
+
+// stepFor calls step with frames cycling through steps until total has
+// passed.
+func stepFor(total time.Duration, steps []time.Duration, step func(dt time.Duration)) {
+	var elapsed time.Duration
+	for i := 0; elapsed < total; i++ {
+		dt := steps[i%len(steps)]
+		if elapsed+dt > total {
+			dt = total - elapsed
+		}
+		step(dt)
+		elapsed += dt
+	}
+}
+
+// TestIterationAnimationRate checks that the iteration animation climbs
+// Rate iterations a second whatever the frame rate, and stops at the count
+// it started from.
+func TestIterationAnimationRate(t *testing.T) {
+	for _, steps := range frameSteps {
+		settings := testSettings(1, 1)
+		a := iterationAnimation{Rate: 30}
+		a.Start(&settings)
+		stepFor(2*time.Second, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		if settings.MaxIterations != 60 || !a.Active {
+			t.Errorf("frames of %v: %d iterations after 2s at 30 a second, active %v",
+				steps, settings.MaxIterations, a.Active)
+		}
+
+		stepFor(10*time.Second, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		a.Step(&settings, steps[0])
+		if settings.MaxIterations != 200 || a.Active {
+			t.Errorf("frames of %v: the animation ended at %d iterations, active %v",
+				steps, settings.MaxIterations, a.Active)
+		}
+	}
+}
+
+// TestZoomAnimationDuration checks that an eased zoom is as far along
+// after the same time whatever the frame rate, and lands on the span a
+// plain zoom gives when its duration is up.
+func TestZoomAnimationDuration(t *testing.T) {
+	const duration = 300 * time.Millisecond
+	want := testSettings(800, 800)
+	want.Zoom(true)
+
+	var halfway []float64
+	for _, steps := range frameSteps {
+		settings := testSettings(800, 800)
+		var a zoomAnimation
+		if !a.Zoom(&settings, true, duration) {
+			t.Fatal("the zoom didn't start")
+		}
+		stepFor(duration/2, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		halfway = append(halfway, settings.Max-settings.Min)
+
+		stepFor(duration/2, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		if span := settings.Max - settings.Min; a.Active || math.Abs(span-(want.Max-want.Min)) > 1e-12 {
+			t.Errorf("frames of %v: after %v the span is %v, active %v; want %v",
+				steps, duration, span, a.Active, want.Max-want.Min)
+		}
+	}
+	for i, span := range halfway {
+		if math.Abs(span-halfway[0]) > 1e-12 {
+			t.Errorf("frames of %v: halfway the span is %v, against %v for frames of %v",
+				frameSteps[i], span, halfway[0], frameSteps[0])
+		}
+	}
+}
+
+// TestAutoZoomRate flies towards a view a thousand times deeper at twice
+// closer a second, checking the span after a few seconds whatever the
+// frame rate, and that the flight ends on the target.
+func TestAutoZoomRate(t *testing.T) {
+	for _, steps := range frameSteps {
+		settings := testSettings(800, 800)
+		from := settings.Max - settings.Min
+		a := autoZoom{Rate: 2}
+		a.SetTargetAt(-0.7453, 0.1127, from/1000, 800)
+		if !a.Toggle(&settings) {
+			t.Fatal("the flight didn't start")
+		}
+
+		stepFor(3*time.Second, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		if span := settings.Max - settings.Min; math.Abs(span/(from/8)-1) > 1e-9 {
+			t.Errorf("frames of %v: after 3s at 2x a second the span is %v; want %v", steps, span, from/8)
+		}
+
+		stepFor(10*time.Second, steps, func(dt time.Duration) { a.Step(&settings, dt) })
+		re, im := settings.ViewCenter()
+		span := settings.Max - settings.Min
+		if a.Active || math.Abs(span/(from/1000)-1) > 1e-9 || math.Abs(re+0.7453) > 1e-9 || math.Abs(im-0.1127) > 1e-9 {
+			t.Errorf("frames of %v: the flight ended at %v%+vi with a span of %v, active %v",
+				steps, re, im, span, a.Active)
+		}
+		if settings.MaxIterations != 800 {
+			t.Errorf("frames of %v: the flight ended at %d iterations; want 800", steps, settings.MaxIterations)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// edgePixels returns the indices of the pixels whose cached iteration count
+// differs from one of their four neighbours by more than
+// settings.EdgeThreshold, within the region of interest.
+func (mi *MandelbrotImage) edgePixels(settings *Settings) []int {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	threshold := settings.EdgeThreshold
+	width := int(mi.Width)
+	b := settings.renderBounds()
+
+	var edges []int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			idx := y*width + x
+			iters := float64(mi.Iterations[idx])
+
+			if (x > b.Min.X && math.Abs(iters-float64(mi.Iterations[idx-1])) > threshold) ||
+				(x < b.Max.X-1 && math.Abs(iters-float64(mi.Iterations[idx+1])) > threshold) ||
+				(y > b.Min.Y && math.Abs(iters-float64(mi.Iterations[idx-width])) > threshold) ||
+				(y < b.Max.Y-1 && math.Abs(iters-float64(mi.Iterations[idx+width])) > threshold) {
+				edges = append(edges, idx)
+			}
+		}
+	}
+	return edges
+}
+
+// refineEdges re-renders the edge pixels of the last frame with
+// AASamples x AASamples samples each, for the frame's settings and its
+// kernel. It gives up as soon as a newer render starts.
+func (mi *MandelbrotImage) refineEdges(kernel Kernel, settings *Settings, generation int64) {
+	edges := mi.edgePixels(settings)
+	width := int(mi.Width)
+
+	workers := mi.workers()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := w; n < len(edges); n += workers {
+				if mi.stale(generation) {
+					return
+				}
+
+				px := float64(edges[n] % width)
+				py := float64(edges[n] / width)
+				pt := supersample(kernel, px, py, settings)
+				pt.Generation = generation
+				mi.Jobs <- pt
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// supersample averages an n x n grid of samples spread across the pixel
+// (px, py). The pixel keeps the iteration count of its centre sample.
+func supersample(kernel Kernel, px, py float64, settings *Settings) Point {
+	n := settings.AASamples
+	if n < 1 {
+		n = 1
+	}
+
+	var red, green, blue float64
+	var sx, sy int64
+	for sy = 0; sy < n; sy++ {
+		for sx = 0; sx < n; sx++ {
+			x := px + (float64(sx)+0.5)/float64(n) - 0.5
+			y := py + (float64(sy)+0.5)/float64(n) - 0.5
+
+			r, g, b := sampleColor(kernel, x, y, settings)
+			red += r
+			green += g
+			blue += b
+		}
+	}
+
+	samples := float64(n * n)
+	iters, nu := samplePixelSmooth(kernel, px, py, settings)
+	pt := pixelPoint(px, py, red/samples, green/samples, blue/samples, iters, settings)
+	pt.Smooth = nu
+	return pt
+}
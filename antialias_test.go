@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestAdaptiveAAMatchesFullAA checks that supersampling only the edges
+// comes out close to supersampling every pixel.
+func TestAdaptiveAAMatchesFullAA(t *testing.T) {
+	settings := testSettings(64, 64)
+	settings.AdaptiveAA = true
+	mi := startImage(&settings)
+	mi.ForceRender()
+	finish(mi)
+	adaptive := mi.Snapshot()
+
+	kernel, err := kernelFor(&settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total, worst float64
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			want := displayColor(supersample(kernel, float64(x), float64(y), &settings))
+			got := adaptive.RGBAAt(x, y)
+			for _, d := range []float64{
+				float64(got.R) - float64(want.R),
+				float64(got.G) - float64(want.G),
+				float64(got.B) - float64(want.B),
+			} {
+				if d < 0 {
+					d = -d
+				}
+				total += d
+				if d > worst {
+					worst = d
+				}
+			}
+		}
+	}
+	if mean := total / (64 * 64 * 3); mean > 0.5 {
+		t.Errorf("adaptive AA is off full AA by %.2f a channel on average (worst %v)", mean, worst)
+	}
+}
+
+// TestRefineEdgesAfterViewChange moves the view while the edges of the
+// last frame are being refined, as the main loop does; run with -race.
+// None of the refined pixels may land on the new frame.
+func TestRefineEdgesAfterViewChange(t *testing.T) {
+	settings := testSettings(96, 96)
+	settings.AdaptiveAA = true
+	mi := startImage(&settings)
+	mi.ForceRender()
+
+	for i := 0; i < 5; i++ {
+		settings.ZoomAt(10, 20, wheelZoomFactor)
+	}
+	settings.AdaptiveAA = false
+	mi.ForceRender()
+	finish(mi)
+
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+}
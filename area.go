@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// areaBatch is how many points the area estimate samples per frame.
+const areaBatch = 20000
+
+// areaEstimate is a Monte Carlo estimate of the area of the set inside the
+// view: points are picked uniformly at random and the fraction that stay
+// bounded is scaled by the view's area. It restarts whenever the view
+// changes.
+type areaEstimate struct {
+	Active   bool
+	target   int64
+	samples  int64
+	interior int64
+	rng      *rand.Rand
+	view     viewParams
+	kernel   Kernel
+}
+
+func newAreaEstimate(rng *rand.Rand, samples int64) *areaEstimate {
+	return &areaEstimate{target: samples, rng: rng}
+}
+
+func (a *areaEstimate) Toggle(settings *Settings) {
+	a.Active = !a.Active
+	if a.Active {
+		a.restart(settings)
+	}
+}
+
+func (a *areaEstimate) restart(settings *Settings) {
+	a.samples = 0
+	a.interior = 0
+	a.view = paramsFor(settings)
+
+	kernel, err := kernelFor(settings)
+	if err != nil {
+		log.WithError(err).Error("could not estimate the area")
+		a.Active = false
+		return
+	}
+	a.kernel = kernel
+}
+
+// Step samples the next batch of points, up to the target sample count.
+func (a *areaEstimate) Step(settings *Settings) {
+	if !a.Active {
+		return
+	}
+	if paramsFor(settings) != a.view {
+		a.restart(settings)
+		if !a.Active {
+			return
+		}
+	}
+	if a.samples >= a.target {
+		return
+	}
+
+	n := a.target - a.samples
+	if n > areaBatch {
+		n = areaBatch
+	}
+
+	var i int64
+	for i = 0; i < n; i++ {
+		x, y := settings.PixelToComplex(a.rng.Float64()*settings.Width, a.rng.Float64()*settings.Height)
+		if _, _, escaped := a.kernel.Iterate(seed(x, y, settings)); !escaped {
+			a.interior++
+		}
+	}
+	a.samples += n
+
+	if a.samples == a.target {
+		area, stderr := a.Estimate(settings)
+		log.WithFields(log.Fields{
+			"area":    area,
+			"error":   stderr,
+			"samples": a.samples,
+		}).Info("estimated the area of the set in view")
+	}
+}
+
+// Estimate returns the current estimate of the area and its standard
+// error, from the binomial variance of the interior fraction.
+func (a *areaEstimate) Estimate(settings *Settings) (float64, float64) {
+	if a.samples == 0 {
+		return 0, 0
+	}
+
+	x0, y0 := settings.PixelToComplex(0, 0)
+	x1, y1 := settings.PixelToComplex(settings.Width, settings.Height)
+	viewArea := math.Abs(x1-x0) * math.Abs(y1-y0)
+
+	p := float64(a.interior) / float64(a.samples)
+	return viewArea * p, viewArea * math.Sqrt(p*(1-p)/float64(a.samples))
+}
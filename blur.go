@@ -0,0 +1,50 @@
+package main
+
+// Blurred returns a box-blurred copy of the pixel buffer, blurring
+// horizontally then vertically over 2*radius+1 pixels with the edges
+// clamped. The image's own buffer is untouched, so blurring every frame
+// never compounds. The returned slice is reused by the next call.
+func (mi *MandelbrotImage) Blurred(radius int) []byte {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	width := int(mi.Width)
+	height := int(mi.Height)
+	if len(mi.blurScratch) != len(mi.Pixels) {
+		mi.blurScratch = make([]byte, len(mi.Pixels))
+		mi.blurred = make([]byte, len(mi.Pixels))
+	}
+
+	boxBlur(mi.blurScratch, mi.Pixels, width, height, 4, width*4, radius)
+	boxBlur(mi.blurred, mi.blurScratch, height, width, width*4, 4, radius)
+	return mi.blurred
+}
+
+// boxBlur blurs src into dst along one axis. There are lines lines of n
+// pixels each; step is the byte distance between neighbouring pixels on a
+// line and stride the distance between the starts of consecutive lines.
+func boxBlur(dst, src []byte, n, lines, step, stride, radius int) {
+	window := 2*radius + 1
+	for line := 0; line < lines; line++ {
+		base := line * stride
+		for ch := 0; ch < 4; ch++ {
+			at := func(i int) int {
+				if i < 0 {
+					i = 0
+				} else if i >= n {
+					i = n - 1
+				}
+				return int(src[base+i*step+ch])
+			}
+
+			sum := 0
+			for i := -radius; i <= radius; i++ {
+				sum += at(i)
+			}
+			for i := 0; i < n; i++ {
+				dst[base+i*step+ch] = byte((sum + window/2) / window)
+				sum += at(i+radius+1) - at(i-radius)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestBlurPreservesBrightness blurs a rendered frame, checking that the
+// total of each channel barely moves, that radius 0 is a copy, and that
+// the frame itself is left alone.
+func TestBlurPreservesBrightness(t *testing.T) {
+	// few iterations, for a bright frame where rounding doesn't swamp
+	// the totals
+	settings := testSettings(96, 64)
+	settings.MaxIterations = 20
+	mi := startImage(&settings)
+	mi.ForceRender()
+	finish(mi)
+	original := append([]byte(nil), mi.Pixels...)
+
+	totals := func(pix []byte) [4]float64 {
+		var sum [4]float64
+		for i, v := range pix {
+			sum[i%4] += float64(v)
+		}
+		return sum
+	}
+	want := totals(original)
+
+	if !bytes.Equal(mi.Blurred(0), original) {
+		t.Error("a blur of radius 0 changed the frame")
+	}
+	for _, radius := range []int{1, 2, 5} {
+		got := totals(mi.Blurred(radius))
+		for ch := 0; ch < 4; ch++ {
+			// clamping the edges and rounding each pass can shift the
+			// total a little, but not by a percent
+			if d := got[ch] - want[ch]; d > want[ch]/100 || d < -want[ch]/100 {
+				t.Errorf("radius %d: channel %d totals %v; want about %v", radius, ch, got[ch], want[ch])
+			}
+		}
+	}
+	if !bytes.Equal(mi.Pixels, original) {
+		t.Error("blurring changed the image's own buffer")
+	}
+
+	// a flat image stays flat
+	for i := range mi.Pixels {
+		mi.Pixels[i] = 77
+	}
+	for i, v := range mi.Blurred(3) {
+		if v != 77 {
+			t.Fatalf("byte %d of a flat image blurred to %d", i, v)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// encodeBMP writes img as an uncompressed 24-bit BMP, which the standard
+// library has no encoder for.
+func encodeBMP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rowSize := (width*3 + 3) &^ 3
+	const headerSize = 14 + 40
+
+	bw := bufio.NewWriter(w)
+	header := []interface{}{
+		// file header
+		[2]byte{'B', 'M'},
+		uint32(headerSize + rowSize*height),
+		uint32(0),
+		uint32(headerSize),
+		// BITMAPINFOHEADER
+		uint32(40),
+		int32(width),
+		int32(height),
+		uint16(1),
+		uint16(24),
+		uint32(0),
+		uint32(rowSize * height),
+		int32(2835),
+		int32(2835),
+		uint32(0),
+		uint32(0),
+	}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	// rows run bottom to top, each pixel blue, green, red
+	row := make([]byte, rowSize)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, y).RGBA()
+			row[x*3] = byte(b >> 8)
+			row[x*3+1] = byte(g >> 8)
+			row[x*3+2] = byte(r >> 8)
+		}
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
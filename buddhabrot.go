@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// buddhabrotBound is the half-width of the square c values are drawn
+// from; every escaping orbit that can reach the view starts inside it.
+const buddhabrotBound = 2
+
+// buddhabrotDensity traces the orbits of samples c values under
+// z = z^2 + c, picked by strategy, and measures per pixel of the view how
+// often the orbits that escape within maxIterations pass through it.
+// Orbits that never escape are left out, as are c values in the main
+// cardioid and period-2 bulb, which are known not to. The orbits are
+// traced with the modulus bailout whatever kernel is selected. The same
+// seed and worker count give the same density.
+func buddhabrotDensity(settings *Settings, samples, maxIterations int64, strategy samplingStrategy, seed int64) []float64 {
+	return buddhabrotDensities(settings, samples, []int64{maxIterations}, strategy, seed)[0]
+}
+
+// buddhabrotDensities is buddhabrotDensity for several iteration limits at
+// once: density k counts the orbits that escape within limits[k]. An orbit
+// is the same whatever the limit, so each is traced only once.
+func buddhabrotDensities(settings *Settings, samples int64, limits []int64, strategy samplingStrategy, seed int64) [][]float64 {
+	workers := workerCount(settings.Workers)
+	perWorker := make([][][]float64, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed + int64(w)))
+			t := newOrbitTracer(settings, limits)
+			strategy.sample(t, rng, w, workers, samples)
+			perWorker[w] = t.densities
+		}(w)
+	}
+	wg.Wait()
+
+	total := perWorker[0]
+	for _, densities := range perWorker[1:] {
+		for k, d := range densities {
+			for i, v := range d {
+				total[k][i] += v
+			}
+		}
+	}
+	return total
+}
+
+// orbitTracer traces orbits and plots them into one density buffer per
+// iteration limit.
+type orbitTracer struct {
+	settings      *Settings
+	width         int
+	height        int
+	limits        []int64
+	maxIterations int64
+	densities     [][]float64
+}
+
+func newOrbitTracer(settings *Settings, limits []int64) *orbitTracer {
+	t := &orbitTracer{
+		settings:  settings,
+		width:     int(settings.Width),
+		height:    int(settings.Height),
+		limits:    limits,
+		densities: make([][]float64, len(limits)),
+	}
+	for k, limit := range limits {
+		t.densities[k] = make([]float64, t.width*t.height)
+		if limit > t.maxIterations {
+			t.maxIterations = limit
+		}
+	}
+	return t
+}
+
+// trace iterates c, reusing orbit's storage for the points it visits, and
+// returns them with the iteration the orbit escaped at, or maxIterations
+// if it didn't.
+func (t *orbitTracer) trace(c complex128, orbit []complex128) ([]complex128, int64) {
+	orbit = orbit[:0]
+	if knownInterior(c) {
+		return orbit, t.maxIterations
+	}
+
+	z := c
+	var i int64
+	for i = 0; i < t.maxIterations; i++ {
+		z = z*z + c
+		if real(z)*real(z)+imag(z)*imag(z) > 4 {
+			return orbit, i
+		}
+		orbit = append(orbit, z)
+	}
+	return orbit, t.maxIterations
+}
+
+// pixel is the index of the pixel z falls in, and whether it is in view.
+func (t *orbitTracer) pixel(z complex128) (int, bool) {
+	px, py := t.settings.ComplexToPixel(real(z), imag(z))
+	x, y := int(math.Floor(px)), int(math.Floor(py))
+	if x < 0 || x >= t.width || y < 0 || y >= t.height {
+		return 0, false
+	}
+	return y*t.width + x, true
+}
+
+// hits counts the points of an orbit that escaped at escape which land in
+// view; orbits that don't escape count for nothing.
+func (t *orbitTracer) hits(orbit []complex128, escape int64) int {
+	if escape >= t.maxIterations {
+		return 0
+	}
+	n := 0
+	for _, z := range orbit {
+		if _, ok := t.pixel(z); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// plot adds weight for each point of an orbit that escaped at escape to
+// every density whose limit it escaped within.
+func (t *orbitTracer) plot(orbit []complex128, escape int64, weight float64) {
+	for _, z := range orbit {
+		idx, ok := t.pixel(z)
+		if !ok {
+			continue
+		}
+		for k, limit := range t.limits {
+			if escape < limit {
+				t.densities[k][idx] += weight
+			}
+		}
+	}
+}
+
+// knownInterior reports whether c lies in the main cardioid or the
+// period-2 bulb, whose orbits never escape.
+func knownInterior(c complex128) bool {
+	x, y := real(c), imag(c)
+	q := (x-0.25)*(x-0.25) + y*y
+	if q*(q+(x-0.25)) <= 0.25*y*y {
+		return true
+	}
+	return (x+1)*(x+1)+y*y <= 0.0625
+}
+
+// densityLevel tone-maps a density against the highest in the image to
+// [0, 1]. The square root lifts the faint outer orbits, and
+// Settings.ToneMap applies on top.
+func densityLevel(d, highest float64, settings *Settings) float64 {
+	if highest == 0 {
+		return 0
+	}
+	return settings.ToneMap.Apply(math.Sqrt(d / highest))
+}
+
+func maxDensity(density []float64) float64 {
+	var highest float64
+	for _, d := range density {
+		if d > highest {
+			highest = d
+		}
+	}
+	return highest
+}
+
+// buddhabrotImage tone-maps a density buffer to a grayscale image, 16 bits
+// deep if asked for.
+func buddhabrotImage(density []float64, width, height int, deep bool, settings *Settings) image.Image {
+	highest := maxDensity(density)
+	rect := image.Rect(0, 0, width, height)
+	if deep {
+		img := image.NewGray16(rect)
+		for i, d := range density {
+			img.SetGray16(i%width, i/width, color.Gray16{Y: uint16(math.Round(densityLevel(d, highest, settings) * 0xffff))})
+		}
+		return img
+	}
+
+	img := image.NewGray(rect)
+	for i, d := range density {
+		img.SetGray(i%width, i/width, color.Gray{Y: uint8(math.Round(densityLevel(d, highest, settings) * 255))})
+	}
+	return img
+}
+
+// runBuddhabrot renders the view as a Buddhabrot of samples orbits at
+// Settings.MaxIterations and writes it to path.
+func runBuddhabrot(path string, samples int64, strategy samplingStrategy, seed int64, settings *Settings, export exportOptions) error {
+	density := buddhabrotDensity(settings, samples, settings.MaxIterations, strategy, seed)
+	log.WithFields(log.Fields{
+		"samples":  samples,
+		"strategy": strategy.String(),
+		"highest":  maxDensity(density),
+	}).Info("traced the Buddhabrot orbits")
+
+	img := buddhabrotImage(density, int(settings.Width), int(settings.Height), export.BitDepth == 16, settings)
+	return writeImage(path, img, export)
+}
+
+// nebulabrotImage maps three densities to red, green and blue, each
+// normalized against its own brightest pixel.
+func nebulabrotImage(densities [][]float64, width, height int, deep bool, settings *Settings) image.Image {
+	var highest [3]float64
+	for k := range highest {
+		highest[k] = maxDensity(densities[k])
+	}
+	rect := image.Rect(0, 0, width, height)
+	level := func(k, i int) float64 {
+		return densityLevel(densities[k][i], highest[k], settings)
+	}
+
+	if deep {
+		img := image.NewNRGBA64(rect)
+		to16 := func(v float64) uint16 { return uint16(math.Round(v * 0xffff)) }
+		for i := 0; i < width*height; i++ {
+			img.SetNRGBA64(i%width, i/width, color.NRGBA64{R: to16(level(0, i)), G: to16(level(1, i)), B: to16(level(2, i)), A: 0xffff})
+		}
+		return img
+	}
+
+	img := image.NewRGBA(rect)
+	to8 := func(v float64) uint8 { return uint8(math.Round(v * 255)) }
+	for i := 0; i < width*height; i++ {
+		img.SetRGBA(i%width, i/width, color.RGBA{R: to8(level(0, i)), G: to8(level(1, i)), B: to8(level(2, i)), A: 255})
+	}
+	return img
+}
+
+// parseNebulabrotLimits parses the red, green and blue iteration limits of
+// a Nebulabrot, given as "r,g,b".
+func parseNebulabrotLimits(s string) ([]int64, error) {
+	limits := make([]int64, 3)
+	if _, err := fmt.Sscanf(s, "%d,%d,%d", &limits[0], &limits[1], &limits[2]); err != nil {
+		return nil, errors.Wrapf(err, "%q is not r,g,b", s)
+	}
+	for _, limit := range limits {
+		if limit < 1 {
+			return nil, errors.Errorf("the iteration limits in %q must be at least 1", s)
+		}
+	}
+	return limits, nil
+}
+
+// runNebulabrot renders the view as a Nebulabrot: the Buddhabrot densities
+// at the red, green and blue iteration limits as the three channels.
+func runNebulabrot(path string, samples int64, limits []int64, strategy samplingStrategy, seed int64, settings *Settings, export exportOptions) error {
+	densities := buddhabrotDensities(settings, samples, limits, strategy, seed)
+	log.WithFields(log.Fields{
+		"samples":  samples,
+		"strategy": strategy.String(),
+		"limits":   limits,
+	}).Info("traced the Nebulabrot orbits")
+
+	img := nebulabrotImage(densities, int(settings.Width), int(settings.Height), export.BitDepth == 16, settings)
+	return writeImage(path, img, export)
+}
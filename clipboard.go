@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// imageClipboardHelpers are the commands tried, in order, to put a PNG on
+// the clipboard, since SDL's clipboard only holds text. Each reads the
+// image on stdin.
+var imageClipboardHelpers = [][]string{
+	{"wl-copy", "--type", "image/png"},
+	{"xclip", "-selection", "clipboard", "-target", "image/png", "-in"},
+}
+
+var errNoImageClipboard = errors.New("no image clipboard helper found; install wl-copy or xclip")
+
+// Displayed returns the frame as the window shows it, with the blur,
+// relief and contours the settings ask for, in the layout of Pixels. fast
+// skips the blur and relief, for previews while navigating. The slice may
+// be Pixels itself or a buffer the next call reuses.
+func (mi *MandelbrotImage) Displayed(fast bool) []byte {
+	settings := mi.Settings
+	pixels := mi.Pixels[:]
+	if settings.BlurRadius > 0 && !fast {
+		pixels = mi.Blurred(int(settings.BlurRadius))
+	}
+	if settings.Relief && !fast {
+		pixels = mi.Shaded(pixels, settings.LightAzimuth, settings.LightElevation)
+	}
+	if settings.Contours {
+		pixels = mi.Contoured(pixels, settings.ContourSpacing)
+	}
+	return pixels
+}
+
+// Snapshot copies the frame as Displayed shows it once it has settled.
+// The window ignores alpha, but the copy keeps it, so pixels the AlphaMode
+// clears come out as the transparent black of a headless export.
+func (mi *MandelbrotImage) Snapshot() *image.RGBA {
+	pixels := mi.Displayed(false)
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, int(mi.Width), int(mi.Height)))
+	for i := 0; i+3 < len(pixels) && i+3 < len(img.Pix); i += 4 {
+		// the texture shows the bytes in the opposite order, and
+		// image.RGBA is premultiplied
+		alpha := uint32(pixels[i+3])
+		img.Pix[i] = uint8(uint32(pixels[i+2]) * alpha / 255)
+		img.Pix[i+1] = uint8(uint32(pixels[i+1]) * alpha / 255)
+		img.Pix[i+2] = uint8(uint32(pixels[i]) * alpha / 255)
+		img.Pix[i+3] = uint8(alpha)
+	}
+	return img
+}
+
+// copyImage puts img on the clipboard as a PNG with the first helper that
+// is installed.
+func copyImage(img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return errors.Wrap(err, "could not encode the image")
+	}
+
+	for _, helper := range imageClipboardHelpers {
+		path, err := exec.LookPath(helper[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, helper[1:]...)
+		cmd.Stdin = bytes.NewReader(buf.Bytes())
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "%s failed: %s", helper[0], bytes.TrimSpace(out))
+		}
+		return nil
+	}
+	return errNoImageClipboard
+}
+
+// copyFrame copies the displayed frame to the clipboard, or the view's
+// parameters as text when the image can't be copied.
+func copyFrame(mi *MandelbrotImage, settings *Settings) {
+	err := copyImage(mi.Snapshot())
+	if err == nil {
+		log.Info("copied the image to the clipboard")
+		return
+	}
+
+	params := paramsFor(settings).CommandLine()
+	log.WithError(err).Warn("could not copy the image; copying the view parameters instead")
+	if err := sdl.SetClipboardText(params); err != nil {
+		log.WithError(err).Error("could not copy the view parameters")
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// bayer4 is the 4x4 ordered dither matrix.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// ToneMap compresses the normalized iteration value before it is colored,
+// so views with a huge range of iteration counts show detail at both ends.
+type ToneMap int
+
+const (
+	ToneMapLinear ToneMap = iota
+	ToneMapLog
+	ToneMapReinhard
+	toneMapCount
+)
+
+const (
+	logToneScale      = 255
+	reinhardToneScale = 8
+)
+
+func (t ToneMap) String() string {
+	switch t {
+	case ToneMapLog:
+		return "log"
+	case ToneMapReinhard:
+		return "reinhard"
+	}
+	return "linear"
+}
+
+// Apply maps v in [0, 1] back onto [0, 1].
+func (t ToneMap) Apply(v float64) float64 {
+	switch t {
+	case ToneMapLog:
+		return math.Log1p(logToneScale*v) / math.Log1p(logToneScale)
+	case ToneMapReinhard:
+		x := reinhardToneScale * v
+		return x / (1 + x) * (1 + reinhardToneScale) / reinhardToneScale
+	}
+	return v
+}
+
+// AlphaMode picks which pixels are transparent. The window ignores alpha;
+// it shows up in exported images.
+type AlphaMode int
+
+const (
+	AlphaOpaque AlphaMode = iota
+	AlphaClearInterior
+	AlphaClearExterior
+	alphaModeCount
+)
+
+func (a AlphaMode) String() string {
+	switch a {
+	case AlphaClearInterior:
+		return "interior"
+	case AlphaClearExterior:
+		return "exterior"
+	}
+	return "opaque"
+}
+
+func parseAlphaMode(name string) (AlphaMode, error) {
+	var a AlphaMode
+	for a = 0; a < alphaModeCount; a++ {
+		if a.String() == name {
+			return a, nil
+		}
+	}
+	return AlphaOpaque, errors.Errorf("unknown alpha mode %q; use opaque, interior or exterior", name)
+}
+
+// Transparent reports whether a pixel with iters iterations is cleared.
+func (a AlphaMode) Transparent(iters, maxIterations int64) bool {
+	switch a {
+	case AlphaClearInterior:
+		return iters == maxIterations
+	case AlphaClearExterior:
+		return iters != maxIterations
+	}
+	return false
+}
+
+// colorFor maps an iteration count to red, green and blue intensities on
+// the 0-255 scale, before quantization.
+func colorFor(iters int64, settings *Settings) (float64, float64, float64) {
+	if c, ok := settings.ColorOverrides[int(iters)]; ok {
+		return overrideChannels(c, settings)
+	}
+	if iters == settings.MaxIterations {
+		return 0, 0, 0
+	}
+	return colorForCount(float64(iters), settings)
+}
+
+// colorForCount colors an escaping pixel by its iteration count, which
+// may be fractional.
+func colorForCount(count float64, settings *Settings) (float64, float64, float64) {
+	t := count / float64(settings.MaxIterations)
+	if settings.ColorDensity > 0 && settings.ColorDensity != 1 {
+		t = wrapDensity(t, settings.ColorDensity)
+	}
+	if settings.ColorOffset != 0 {
+		t = wrapOffset(t, settings.ColorOffset)
+	}
+	if settings.Invert {
+		t = 1 - t
+	}
+	return colorAt(t, settings)
+}
+
+// overrideChannels converts a color override to the channel intensities
+// colorFor returns, so it comes out as c on screen.
+func overrideChannels(c color.RGBA, settings *Settings) (float64, float64, float64) {
+	channel := func(v uint8) float64 {
+		if settings.LinearLight {
+			return srgbToLinear(float64(v)/255) * 255
+		}
+		return float64(v)
+	}
+	// the frame buffer holds the channels in the opposite order to the
+	// screen
+	return channel(c.B), channel(c.G), channel(c.R)
+}
+
+// parseColorOverrides parses iterations=#rrggbb pairs separated by commas.
+func parseColorOverrides(s string) (map[int]color.RGBA, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	overrides := make(map[int]color.RGBA)
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("%q is not iterations=#rrggbb", entry)
+		}
+		iters, err := strconv.Atoi(parts[0])
+		if err != nil || iters < 0 {
+			return nil, errors.Errorf("%q is not an iteration count", parts[0])
+		}
+		c, err := parseHexColor(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		overrides[iters] = c
+	}
+	return overrides, nil
+}
+
+// formatColorOverrides lists overrides in order of iteration count.
+func formatColorOverrides(overrides map[int]color.RGBA) string {
+	counts := make([]int, 0, len(overrides))
+	for iters := range overrides {
+		counts = append(counts, iters)
+	}
+	sort.Ints(counts)
+
+	entries := make([]string, len(counts))
+	for i, iters := range counts {
+		c := overrides[iters]
+		entries[i] = fmt.Sprintf("%d=#%02x%02x%02x", iters, c.R, c.G, c.B)
+	}
+	return strings.Join(entries, ",")
+}
+
+// wrapDensity repeats the palette density times over [0, 1]. A band ends
+// on 1 rather than wrapping to 0, so the top of the range stays bright.
+func wrapDensity(t, density float64) float64 {
+	wrapped := math.Mod(t*density, 1)
+	if wrapped == 0 && t > 0 {
+		return 1
+	}
+	return wrapped
+}
+
+// colorOffsetStep is how far one press moves Settings.ColorOffset.
+const colorOffsetStep = 0.05
+
+// wrapOffset shifts t in [0, 1] by offset, wrapping around the palette.
+// Like wrapDensity it lands on 1 rather than 0 at the wrap, so a whole
+// number of turns leaves t as it was.
+func wrapOffset(t, offset float64) float64 {
+	wrapped := math.Mod(t+offset, 1)
+	if wrapped < 0 {
+		wrapped++
+	}
+	if wrapped == 0 && t > 0 {
+		return 1
+	}
+	return wrapped
+}
+
+// colorAt maps t in [0, 1] through the tone map and Settings.Palette to
+// red, green and blue intensities on the 0-255 scale.
+func colorAt(t float64, settings *Settings) (float64, float64, float64) {
+	col := settings.ToneMap.Apply(t) * 255
+	if col < settings.MinColorThreshold {
+		col = 0
+	}
+
+	// the frame buffer holds the channels in the opposite order to the
+	// screen
+	blue, green, red := paletteFor(settings).Color(col / 255)
+
+	curve := settings.ToneCurve
+	return curve.Apply(red/255) * 255, curve.Apply(green/255) * 255, curve.Apply(blue/255) * 255
+}
+
+// parseHexColor parses a #rrggbb color.
+func parseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, errors.Errorf("%q is not a #rrggbb color", s)
+	}
+	// Sscanf's %x stops at the first bad digit without an error, so the
+	// digits are parsed whole
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, errors.Wrapf(err, "%q is not a #rrggbb color", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// srgbToLinear decodes an sRGB-encoded intensity in [0, 1] to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear-light intensity in [0, 1] with the sRGB
+// transfer function.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// encodeChannel prepares a 0-255 channel intensity for quantization. With
+// LinearLight set, colors are computed, and blended, as linear light and
+// only converted to sRGB here.
+func encodeChannel(v float64, settings *Settings) float64 {
+	if !settings.LinearLight {
+		return v
+	}
+	// the curve comes back from linear white, or any level decoded from
+	// sRGB, a rounding error below where it started, which quantizing
+	// would truncate to the level below
+	return math.Min(linearToSRGB(math.Max(0, math.Min(v/255, 1)))*255+1e-9, 255)
+}
+
+// pixelPoint builds the output point for the pixel at (px, py) from its
+// 0-255 channel intensities.
+func pixelPoint(px, py, red, green, blue float64, iters int64, settings *Settings) Point {
+	return Point{
+		X:     px,
+		Y:     py,
+		Red:   quantize(encodeChannel(red, settings), px, py, settings.Dither),
+		Green: quantize(encodeChannel(green, settings), px, py, settings.Dither),
+		Blue:  quantize(encodeChannel(blue, settings), px, py, settings.Dither),
+
+		Iterations:  iters,
+		Smooth:      float64(iters),
+		Transparent: settings.AlphaMode.Transparent(iters, settings.MaxIterations),
+	}
+}
+
+// quantize converts a 0-255 intensity to a byte. With dither set, the
+// Bayer threshold for the pixel at (px, py) is added first, so neighbouring
+// pixels round in different directions and gradients don't band.
+func quantize(v, px, py float64, dither bool) uint8 {
+	if dither {
+		v += (bayer4[int(py)&3][int(px)&3] + 0.5) / 16
+	}
+
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestMinColorThreshold checks that a threshold of 0 leaves the whole
+// gradient, with no escaping count forced to black, and that a threshold
+// blacks out just the counts below it.
+func TestMinColorThreshold(t *testing.T) {
+	settings := testSettings(1, 1)
+	black := func(iters int64) bool {
+		red, green, blue := colorFor(iters, &settings)
+		return red == 0 && green == 0 && blue == 0
+	}
+
+	settings.MinColorThreshold = 0
+	var iters int64
+	for iters = 1; iters < settings.MaxIterations; iters++ {
+		if black(iters) {
+			t.Fatalf("with no threshold %d iterations came out black", iters)
+		}
+	}
+
+	settings.MinColorThreshold = 20
+	for iters = 1; iters < settings.MaxIterations; iters++ {
+		below := float64(iters)/float64(settings.MaxIterations)*255 < 20
+		if black(iters) != below {
+			t.Errorf("with a threshold of 20, %d iterations black is %v; want %v", iters, black(iters), below)
+		}
+	}
+}
+
+// TestSRGBRoundTrip checks that the sRGB transfer functions invert each
+// other, meet at the ends of the range and at their linear segment, and
+// that LinearLight leaves black and white alone.
+func TestSRGBRoundTrip(t *testing.T) {
+	for i := 0; i <= 1000; i++ {
+		v := float64(i) / 1000
+		if back := srgbToLinear(linearToSRGB(v)); math.Abs(back-v) > 1e-12 {
+			t.Errorf("linear %v came back as %v", v, back)
+		}
+		if back := linearToSRGB(srgbToLinear(v)); math.Abs(back-v) > 1e-12 {
+			t.Errorf("sRGB %v came back as %v", v, back)
+		}
+	}
+
+	// the two pieces of each curve meet
+	if d := math.Abs(srgbToLinear(0.04045) - math.Pow((0.04045+0.055)/1.055, 2.4)); d > 1e-6 {
+		t.Errorf("srgbToLinear jumps by %v where its pieces meet", d)
+	}
+	if d := math.Abs(linearToSRGB(0.0031308) - (1.055*math.Pow(0.0031308, 1/2.4) - 0.055)); d > 1e-6 {
+		t.Errorf("linearToSRGB jumps by %v where its pieces meet", d)
+	}
+	// mid gray in linear light is about 188 in sRGB
+	if v := linearToSRGB(0.5) * 255; math.Abs(v-187.5) > 0.5 {
+		t.Errorf("linear mid gray encoded to %v; want about 188", v)
+	}
+
+	settings := testSettings(1, 1)
+	settings.LinearLight = true
+	for _, v := range []float64{0, 255} {
+		if got := encodeChannel(v, &settings); math.Abs(got-v) > 1e-6 {
+			t.Errorf("encodeChannel(%v) is %v", v, got)
+		}
+	}
+	if got := encodeChannel(300, &settings); got != 255 {
+		t.Errorf("encodeChannel(300) is %v; want it clamped to 255", got)
+	}
+	// every sRGB level decoded to linear light comes back as itself
+	for level := 0; level < 256; level++ {
+		v := srgbToLinear(float64(level)/255) * 255
+		if got := quantize(encodeChannel(v, &settings), 0, 0, false); int(got) != level {
+			t.Errorf("level %d came back as %d", level, got)
+		}
+	}
+	settings.LinearLight = false
+	if got := encodeChannel(100, &settings); got != 100 {
+		t.Errorf("without LinearLight encodeChannel(100) is %v", got)
+	}
+}
+
+// TestToneMaps checks that every tone map keeps the ends of the range and
+// the order of values, and that the compressing ones lift the low end
+// above linear, so a view dominated by low counts shows more of them.
+func TestToneMaps(t *testing.T) {
+	for tm := ToneMapLinear; tm < toneMapCount; tm++ {
+		if got := tm.Apply(0); got != 0 {
+			t.Errorf("%v maps 0 to %v", tm, got)
+		}
+		if got := tm.Apply(1); math.Abs(got-1) > 1e-12 {
+			t.Errorf("%v maps 1 to %v", tm, got)
+		}
+		previous := 0.0
+		for i := 1; i <= 1000; i++ {
+			v := float64(i) / 1000
+			got := tm.Apply(v)
+			if got <= previous {
+				t.Fatalf("%v maps %v to %v, no higher than the value before", tm, v, got)
+			}
+			if tm == ToneMapLinear && got != v {
+				t.Fatalf("linear maps %v to %v", v, got)
+			}
+			if tm != ToneMapLinear && v < 1 && got <= v {
+				t.Fatalf("%v maps %v to %v, no brighter than linear", tm, v, got)
+			}
+			previous = got
+		}
+	}
+
+	// a tenth of the way along, both compressing maps are well over twice
+	// as bright as linear
+	settings := testSettings(1, 1)
+	linear, _, _ := colorAt(0.1, &settings)
+	for _, tm := range []ToneMap{ToneMapLog, ToneMapReinhard} {
+		settings.ToneMap = tm
+		if red, _, _ := colorAt(0.1, &settings); red < 2*linear {
+			t.Errorf("%v colors 0.1 with red %v against %v for linear", tm, red, linear)
+		}
+	}
+}
+
+// TestAlphaModes renders the startup view in each alpha mode, at 8 and 16
+// bits and in the window's buffer, checking that just the pixels the mode
+// clears are transparent.
+func TestAlphaModes(t *testing.T) {
+	for mode := AlphaOpaque; mode < alphaModeCount; mode++ {
+		if parsed, err := parseAlphaMode(mode.String()); err != nil || parsed != mode {
+			t.Errorf("%v parsed as %v, %v", mode, parsed, err)
+		}
+
+		settings := testSettings(48, 32)
+		settings.AlphaMode = mode
+		r, err := renderImage(&settings, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mi := startImage(&settings)
+		mi.ForceRender()
+		finish(mi)
+
+		clear := 0
+		for i, n := range r.Iterations {
+			x, y := i%48, i/48
+			interior := n == settings.MaxIterations
+			want := mode == AlphaClearInterior && interior || mode == AlphaClearExterior && !interior
+			if want {
+				clear++
+			}
+			if got := r.Image.RGBAAt(x, y).A == 0; got != want {
+				t.Fatalf("%v: (%d, %d), interior %v, transparent %v at 8 bits", mode, x, y, interior, got)
+			}
+			if got := r.Deep.NRGBA64At(x, y).A == 0; got != want {
+				t.Fatalf("%v: (%d, %d), interior %v, transparent %v at 16 bits", mode, x, y, interior, got)
+			}
+			if got := mi.Pixels[i*4+3] == 0; got != want {
+				t.Fatalf("%v: (%d, %d), interior %v, transparent %v in the window", mode, x, y, interior, got)
+			}
+		}
+		if clear == 0 && mode != AlphaOpaque {
+			t.Errorf("%v cleared no pixel", mode)
+		}
+	}
+
+	if _, err := parseAlphaMode("translucent"); err == nil {
+		t.Error("an unknown alpha mode parsed")
+	}
+}
+
+// TestColorOverrides renders with overrides for a band and the interior,
+// checking that exactly those pixels come out in the override colors,
+// ahead of the palette, smooth coloring and linear light alike.
+func TestColorOverrides(t *testing.T) {
+	overrides, err := parseColorOverrides("3=#ff0000, 200=#00ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatColorOverrides(overrides); got != "3=#ff0000,200=#00ff80" {
+		t.Errorf("the overrides format as %q", got)
+	}
+	for _, bad := range []string{"3", "x=#ff0000", "-1=#ff0000", "3=red"} {
+		if _, err := parseColorOverrides(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+
+	for _, variant := range []string{"palette", "smooth", "linear light"} {
+		settings := testSettings(64, 64)
+		settings.ColorOverrides = overrides
+		settings.SmoothColoring = variant == "smooth"
+		settings.LinearLight = variant == "linear light"
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make(map[int]int)
+		for i, n := range r.Iterations {
+			got := r.Image.RGBAAt(i%64, i/64)
+			want, ok := overrides[int(n)]
+			if ok && got != want {
+				t.Fatalf("%s: a pixel that took %d iterations is %v; want %v", variant, n, got, want)
+			}
+			if !ok && (got == overrides[3] || got == overrides[200]) {
+				t.Fatalf("%s: a pixel that took %d iterations is in an override color", variant, n)
+			}
+			if ok {
+				seen[int(n)]++
+			}
+		}
+		if seen[3] == 0 || seen[200] == 0 {
+			t.Errorf("%s: the view has %d pixels at 3 iterations and %d in the set; want some of each",
+				variant, seen[3], seen[200])
+		}
+	}
+
+	settings := testSettings(1, 1)
+	if red, green, blue := overrideChannels(color.RGBA{R: 10, G: 20, B: 30}, &settings); red != 30 || green != 20 || blue != 10 {
+		t.Errorf("the override came out as %v, %v, %v in frame buffer order", red, green, blue)
+	}
+}
+
+// TestColorOffset checks that the offset shifts the palette position and
+// wraps past the end, that whole turns change nothing, and that stepping
+// it twenty times in either direction comes back round to no offset.
+func TestColorOffset(t *testing.T) {
+	tests := []struct {
+		t, offset, want float64
+	}{
+		{t: 0.2, offset: 0.3, want: 0.5},
+		{t: 0.8, offset: 0.3, want: 0.1},
+		{t: 0.2, offset: -0.3, want: 0.9},
+		{t: 0.5, offset: 0.5, want: 1},
+		{t: 0.5, offset: 1, want: 0.5},
+		{t: 0.5, offset: -2, want: 0.5},
+		{t: 1, offset: 1, want: 1},
+		{t: 0, offset: 0, want: 0},
+		{t: 0, offset: 0.25, want: 0.25},
+	}
+	for _, tt := range tests {
+		if got := wrapOffset(tt.t, tt.offset); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("wrapOffset(%v, %v) is %v; want %v", tt.t, tt.offset, got, tt.want)
+		}
+	}
+	for i := 0; i <= 100; i++ {
+		v := float64(i) / 100
+		for _, offset := range []float64{0.05, 0.5, 0.95, -0.35} {
+			if got := wrapOffset(v, offset); got < 0 || got > 1 {
+				t.Fatalf("wrapOffset(%v, %v) is %v, outside [0, 1]", v, offset, got)
+			}
+		}
+	}
+
+	settings := testSettings(1, 1)
+	for _, dir := range []int{1, -1} {
+		settings.ColorOffset = 0
+		for i := 0; i < 20; i++ {
+			settings.ShiftColorOffset(dir)
+			if settings.ColorOffset < 0 || settings.ColorOffset >= 1 {
+				t.Fatalf("stepping %d gave an offset of %v", dir, settings.ColorOffset)
+			}
+		}
+		if off := settings.ColorOffset; off > 1e-9 && off < 1-1e-9 {
+			t.Errorf("twenty steps of %d left an offset of %v", dir, off)
+		}
+	}
+
+	// a palette position and its offset twin color the same
+	settings.MaxIterations = 100
+	settings.ColorOffset = 0.3
+	r1, g1, b1 := colorFor(20, &settings)
+	settings.ColorOffset = 0
+	r2, g2, b2 := colorFor(50, &settings)
+	if r1 != r2 || g1 != g2 || b1 != b2 {
+		t.Errorf("20 iterations offset by 0.3 colored %v, %v, %v; 50 colored %v, %v, %v", r1, g1, b1, r2, g2, b2)
+	}
+}
+
+// TestParseHexColor checks #rrggbb colors parse, and that a bad digit
+// anywhere, or the wrong length, is rejected.
+func TestParseHexColor(t *testing.T) {
+	if c, err := parseHexColor("#20a0Ff"); err != nil || c != (color.RGBA{R: 0x20, G: 0xa0, B: 0xff, A: 255}) {
+		t.Errorf("#20a0Ff parsed as %v, %v", c, err)
+	}
+	for _, s := range []string{"#00000g", "#g00000", "#+12345", "#12345", "#1234567", "123456", ""} {
+		if c, err := parseHexColor(s); err == nil {
+			t.Errorf("%q parsed as %v", s, c)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "sync/atomic"
+
+// colorKey holds the settings the color of an iteration count depends on.
+type colorKey struct {
+	MaxIterations     int64
+	ToneMap           ToneMap
+	ToneCurve         toneCurve
+	Palette           string
+	Interpolation     Interpolation
+	PaletteRevision   int64
+	Overrides         string
+	LinearLight       bool
+	Invert            bool
+	ColorDensity      float64
+	ColorOffset       float64
+	MinColorThreshold float64
+}
+
+func colorKeyFor(settings *Settings) colorKey {
+	return colorKey{
+		MaxIterations:     settings.MaxIterations,
+		ToneMap:           settings.ToneMap,
+		ToneCurve:         settings.ToneCurve,
+		Palette:           paletteName(settings),
+		Interpolation:     settings.Interpolation,
+		PaletteRevision:   atomic.LoadInt64(&paletteRevision),
+		Overrides:         formatColorOverrides(settings.ColorOverrides),
+		LinearLight:       settings.LinearLight,
+		Invert:            settings.Invert,
+		ColorDensity:      settings.ColorDensity,
+		ColorOffset:       settings.ColorOffset,
+		MinColorThreshold: settings.MinColorThreshold,
+	}
+}
+
+// colorTable caches colorFor for every iteration count from 0 to
+// MaxIterations, so coloring a frame is a lookup per pixel.
+type colorTable struct {
+	key    colorKey
+	colors [][3]float64
+}
+
+func newColorTable(settings *Settings) *colorTable {
+	size := settings.MaxIterations + 1
+	if size < 1 {
+		size = 1
+	}
+
+	t := &colorTable{
+		key:    colorKeyFor(settings),
+		colors: make([][3]float64, size),
+	}
+	for i := range t.colors {
+		red, green, blue := colorFor(int64(i), settings)
+		t.colors[i] = [3]float64{red, green, blue}
+	}
+	return t
+}
+
+// Color is colorFor for the settings the table was built from.
+func (t *colorTable) Color(iters int64) (float64, float64, float64) {
+	if iters < 0 {
+		iters = 0
+	}
+	if iters >= int64(len(t.colors)) {
+		iters = int64(len(t.colors)) - 1
+	}
+	c := t.colors[iters]
+	return c[0], c[1], c[2]
+}
+
+// colorTable returns the lookup table for settings, rebuilding it only
+// when one of the coloring settings has changed since the last call.
+func (mi *MandelbrotImage) colorTable(settings *Settings) *colorTable {
+	if mi.colors == nil || mi.colors.key != colorKeyFor(settings) {
+		mi.colors = newColorTable(settings)
+	}
+	return mi.colors
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+// BenchmarkRecolor colors every pixel of a rendered startup view, looking
+// the colors up in a built table and working each one out with no table.
+func BenchmarkRecolor(b *testing.B) {
+	settings := testSettings(800, 800)
+	r, err := renderImage(&settings, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name   string
+		colors *colorTable
+	}{
+		{name: "table", colors: newColorTable(&settings)},
+		{name: "no table"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var sum float64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, iters := range r.Iterations {
+					red, green, blue := pixelColor(bench.colors, iters, 0, &settings)
+					sum += red + green + blue
+				}
+			}
+			if sum < 0 {
+				b.Fatal("negative color")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// config is the preferences kept between runs, as JSON in the user's
+// config directory.
+type config struct {
+	// Palette and Interpolation are the palette startup and the reset key
+	// select when the flags don't choose one.
+	Palette       string `json:"palette,omitempty"`
+	Interpolation string `json:"interpolation,omitempty"`
+}
+
+// defaultConfigPath is the config file in the user's config directory, or
+// empty if the platform has none.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gomandelbrotsdl2", "config.json")
+}
+
+// readConfig loads the config at path. A missing file is an empty config.
+func readConfig(path string) (config, error) {
+	var c config
+	if path == "" {
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, errors.Wrap(err, "could not read the config")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, errors.Wrapf(err, "could not parse %s", path)
+	}
+	return c, nil
+}
+
+// writeConfig saves c to path, creating its directory if need be.
+func writeConfig(path string, c config) error {
+	if path == "" {
+		return errors.New("there is no config directory; pass -config")
+	}
+	blob, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not encode the config")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "could not create the config directory")
+	}
+	return errors.Wrap(ioutil.WriteFile(path, append(blob, '\n'), 0o644), "could not write the config")
+}
+
+// paletteConfig is the config that makes the palette of settings the
+// default.
+func paletteConfig(settings *Settings) config {
+	return config{Palette: paletteName(settings), Interpolation: settings.Interpolation.String()}
+}
+
+// ApplyPalette puts settings on the config's palette, the built-in default
+// if it has none.
+func (c config) ApplyPalette(settings *Settings) error {
+	name, err := parsePalette(c.Palette)
+	if err != nil {
+		return errors.Wrap(err, "the config's palette")
+	}
+	interpolation := InterpolationDefault
+	if c.Interpolation != "" {
+		if interpolation, err = parseInterpolation(c.Interpolation); err != nil {
+			return errors.Wrap(err, "the config's interpolation")
+		}
+	}
+	settings.Palette = name
+	settings.Interpolation = interpolation
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConfig saves a default palette into a config directory that doesn't
+// exist yet and checks that it reads back and resets to it.
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "no", "such", "config.json")
+	c, err := readConfig(path)
+	if err != nil || c != (config{}) {
+		t.Fatalf("a missing config read as %+v, %v", c, err)
+	}
+	settings := testSettings(8, 8)
+	if err := c.ApplyPalette(&settings); err != nil || paletteName(&settings) != palettes[0].Name || settings.Interpolation != InterpolationDefault {
+		t.Errorf("an empty config reset to %q, %v, %v", paletteName(&settings), settings.Interpolation, err)
+	}
+
+	settings.Palette = "gold"
+	settings.Interpolation = InterpolationSmoothstep
+	if err := writeConfig(path, paletteConfig(&settings)); err != nil {
+		t.Fatal(err)
+	}
+	c, err = readConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Palette = "grayscale"
+	settings.Interpolation = InterpolationLinear
+	if err := c.ApplyPalette(&settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Palette != "gold" || settings.Interpolation != InterpolationSmoothstep {
+		t.Errorf("reset to %q, %v; want the saved gold, smoothstep", settings.Palette, settings.Interpolation)
+	}
+
+	if err := writeConfig("", c); err == nil {
+		t.Error("saving with no config path succeeded")
+	}
+}
+
+// TestConfigErrors checks that a broken config or one naming an unknown
+// palette is reported.
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"palette": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfig(path); err == nil || !strings.Contains(err.Error(), "could not parse") {
+		t.Errorf("a broken config gave %v", err)
+	}
+	if _, err := readConfig(dir); err == nil || exitCodeFor(err) != exitIO {
+		t.Errorf("reading a directory as the config gave %v; want an IO error", err)
+	}
+
+	var settings Settings
+	for _, c := range []config{{Palette: "plaid"}, {Interpolation: "cubic"}} {
+		if err := c.ApplyPalette(&settings); err == nil {
+			t.Errorf("%+v applied", c)
+		}
+	}
+}
+
+// TestStartupPalette runs main with a saved default palette, checking that
+// it colors the -palette-strip unless -palette picks another.
+func TestStartupPalette(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeConfig(filepath.Join(dir, "gomandelbrotsdl2", "config.json"), config{Palette: "gold"}); err != nil {
+		t.Fatal(err)
+	}
+	strip := func(args ...string) []byte {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "strip.png")
+		if code, stderr := runMain(t, dir, append(args, "-palette-strip", path)...); code != exitOK {
+			t.Fatalf("%v: exit status %d: %s", args, code, stderr)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if !bytes.Equal(strip(), strip("-palette", "gold")) {
+		t.Error("the saved gold palette didn't color the strip")
+	}
+	if !bytes.Equal(strip("-palette", "grayscale"), strip("-config", filepath.Join(dir, "none.json"), "-palette", "grayscale")) {
+		t.Error("-palette didn't override the saved palette")
+	}
+	if bytes.Equal(strip(), strip("-palette", "grayscale")) {
+		t.Error("the strip is the same whatever the palette")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sheetLabelScale = 2
+	sheetPadding    = 6
+)
+
+// sweepParam is a setting a contact sheet can vary from cell to cell.
+// Geometric parameters step by a constant ratio rather than a constant
+// difference.
+type sweepParam struct {
+	Name      string
+	Geometric bool
+	Apply     func(s *Settings, v float64)
+	Label     func(v float64) string
+}
+
+var sweepParams = []sweepParam{
+	{
+		Name:  "iterations",
+		Apply: func(s *Settings, v float64) { s.MaxIterations = int64(math.Max(1, math.Round(v))) },
+		Label: func(v float64) string { return fmt.Sprintf("%d iterations", int64(math.Max(1, math.Round(v)))) },
+	},
+	{
+		Name:  "color-density",
+		Apply: func(s *Settings, v float64) { s.ColorDensity = v },
+		Label: func(v float64) string { return fmt.Sprintf("density %.3g", v) },
+	},
+	{
+		Name:  "color-offset",
+		Apply: func(s *Settings, v float64) { s.ColorOffset = v },
+		Label: func(v float64) string { return fmt.Sprintf("offset %.3g", v) },
+	},
+	{
+		Name:      "zoom",
+		Geometric: true,
+		Apply: func(s *Settings, v float64) {
+			re, im := s.ViewCenter()
+			s.SetView(complex(re, im), v)
+		},
+		Label: func(v float64) string { return fmt.Sprintf("zoom %.3gx", v) },
+	},
+	{
+		Name:  "rotation",
+		Apply: func(s *Settings, v float64) { s.Rotation = v },
+		Label: func(v float64) string { return fmt.Sprintf("rotation %.3g", v) },
+	},
+}
+
+func sweepParamNamed(name string) (sweepParam, error) {
+	names := make([]string, len(sweepParams))
+	for i, p := range sweepParams {
+		if p.Name == name {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return sweepParam{}, errors.Errorf("can't sweep %q; use %s", name, strings.Join(names, ", "))
+}
+
+// sweepValue is the value of cell i of n, running from from to to.
+func (p sweepParam) sweepValue(from, to float64, i, n int) float64 {
+	if n == 1 {
+		return from
+	}
+	t := float64(i) / float64(n-1)
+	if p.Geometric {
+		return logLerp(from, to, t)
+	}
+	return from + (to-from)*t
+}
+
+// runContactSheet renders the view cols x rows times with the named
+// setting swept from from to to, left to right and then top to bottom,
+// and writes the cells side by side to path, each labeled underneath with
+// its value. Every cell is the size of the render.
+func runContactSheet(path, name string, from, to float64, cols, rows int, settings *Settings, export exportOptions, stats io.Writer) error {
+	param, err := sweepParamNamed(name)
+	if err != nil {
+		return err
+	}
+	if cols < 1 || rows < 1 {
+		return errors.Errorf("the contact sheet needs at least one cell, not %dx%d", cols, rows)
+	}
+	if param.Geometric && (from <= 0 || to <= 0) {
+		return errors.Errorf("%s is swept geometrically, so the range must be positive", name)
+	}
+
+	cellW, cellH := int(settings.Width), int(settings.Height)
+	labelH := glyphHeight*sheetLabelScale + 2*sheetPadding
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*cellW, rows*(cellH+labelH)))
+	draw.Draw(sheet, sheet.Rect, image.NewUniform(color.RGBA{A: 255}), image.Point{}, draw.Src)
+
+	n := cols * rows
+	for i := 0; i < n; i++ {
+		v := param.sweepValue(from, to, i, n)
+		cell := settings.Clone()
+		param.Apply(&cell, v)
+
+		r, err := renderImage(&cell, false)
+		if err != nil {
+			return errors.Wrapf(err, "cell %d", i)
+		}
+		if err := writeStats(stats, r.Stats); err != nil {
+			return err
+		}
+
+		x, y := (i%cols)*cellW, (i/cols)*(cellH+labelH)
+		draw.Draw(sheet, image.Rect(x, y, x+cellW, y+cellH), r.Image, image.Point{}, draw.Src)
+
+		// labels shrink to fit narrow cells, and are cut off at the
+		// cell's edge if even that isn't enough
+		label, scale := param.Label(v), sheetLabelScale
+		if int(textWidth(label, int32(scale))) > cellW-2*sheetPadding {
+			scale = 1
+		}
+		labelArea := sheet.SubImage(image.Rect(x, y+cellH, x+cellW-sheetPadding, y+cellH+labelH)).(*image.RGBA)
+		drawImageText(labelArea, x+sheetPadding, y+cellH+sheetPadding, scale, label, color.RGBA{255, 255, 255, 255})
+	}
+	return writeImage(path, sheet, export)
+}
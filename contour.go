@@ -0,0 +1,35 @@
+package main
+
+// Contoured returns a copy of src with iso-iteration contour lines drawn
+// in white wherever the iteration count crosses a multiple of spacing
+// between a pixel and its right or lower neighbour. src is a frame in the
+// layout of Pixels; the returned slice is reused by the next call.
+func (mi *MandelbrotImage) Contoured(src []byte, spacing int64) []byte {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if len(mi.contoured) != len(src) {
+		mi.contoured = make([]byte, len(src))
+	}
+	copy(mi.contoured, src)
+	if spacing < 1 {
+		spacing = 1
+	}
+
+	width := int(mi.Width)
+	height := int(mi.Height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			idx := y*width + x
+			level := mi.Iterations[idx] / spacing
+
+			if (x < width-1 && mi.Iterations[idx+1]/spacing != level) ||
+				(y < height-1 && mi.Iterations[idx+width]/spacing != level) {
+				mi.contoured[idx*4] = 255
+				mi.contoured[idx*4+1] = 255
+				mi.contoured[idx*4+2] = 255
+			}
+		}
+	}
+	return mi.contoured
+}
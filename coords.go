@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// coordinatePrecision is how many significant digits v needs for the
+// printed value to land within a tenth of a pixel of the view.
+func coordinatePrecision(v float64, settings *Settings) int {
+	spacing := (settings.Max - settings.Min) / math.Max(settings.Width, settings.Height)
+	if spacing <= 0 {
+		return 17
+	}
+
+	magnitude := math.Max(math.Abs(v), spacing)
+	digits := int(math.Floor(math.Log10(magnitude))-math.Floor(math.Log10(spacing))) + 2
+	if digits < 1 {
+		return 1
+	}
+	if digits > 17 {
+		return 17
+	}
+	return digits
+}
+
+func formatCoordinate(v float64, settings *Settings) string {
+	return strconv.FormatFloat(v, 'g', coordinatePrecision(v, settings), 64)
+}
+
+// viewCoordinates describes the middle of the view and its span with
+// enough digits to reproduce it.
+func viewCoordinates(settings *Settings) string {
+	re, im := settings.ViewCenter()
+	span := settings.Max - settings.Min
+	return fmt.Sprintf("%s %s %s", formatCoordinate(re, settings), formatCoordinate(im, settings),
+		strconv.FormatFloat(span, 'g', 6, 64))
+}
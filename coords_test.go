@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// TestCoordinatePrecision zooms in on the seahorse valley, checking at each
+// depth that the printed coordinates land within a tenth of a pixel, that
+// deeper views get more digits, and that none get more than float64 has.
+func TestCoordinatePrecision(t *testing.T) {
+	settings := testSettings(800, 600)
+	const re, im = -0.743643887037151, 0.13182590420533
+
+	previous := 0
+	for _, span := range []float64{3.5, 1e-2, 1e-5, 1e-8, 1e-11, 1e-14} {
+		settings.CenterOn(re, im, span)
+		spacing := span / settings.Width
+		for _, v := range []float64{re, im} {
+			got, err := strconv.ParseFloat(formatCoordinate(v, &settings), 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-v) > spacing/10 {
+				t.Errorf("a span of %g: %v printed as %v, %.2f pixels off", span, v, got, math.Abs(got-v)/spacing)
+			}
+		}
+
+		digits := coordinatePrecision(re, &settings)
+		if digits <= previous || digits > 17 {
+			t.Errorf("a span of %g takes %d digits, after %d for the wider view", span, digits, previous)
+		}
+		previous = digits
+	}
+
+	// the home view needs only a few
+	settings = testSettings(800, 600)
+	if digits := coordinatePrecision(-0.75, &settings); digits > 5 {
+		t.Errorf("the home view takes %d digits", digits)
+	}
+	settings.Max = settings.Min
+	if digits := coordinatePrecision(1, &settings); digits != 17 {
+		t.Errorf("an empty span takes %d digits; want all 17", digits)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// RefineRegion re-renders the pixels in [x0, x1) x [y0, y1) in the
+// background with Settings.DetailSamples x DetailSamples samples each. It
+// gives up as soon as a newer render starts.
+func (mi *MandelbrotImage) RefineRegion(x0, y0, x1, y1 int) error {
+	settings := mi.Settings.Clone()
+	settings.AASamples = settings.DetailSamples
+	kernel, err := kernelFor(&settings)
+	if err != nil {
+		return err
+	}
+	generation := atomic.LoadInt64(&mi.generation)
+
+	width := x1 - x0
+	pixels := width * (y1 - y0)
+	workers := mi.workers()
+	mi.senders.Add(1)
+	go func() {
+		defer mi.senders.Done()
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for n := w; n < pixels; n += workers {
+					if mi.stale(generation) {
+						return
+					}
+
+					px := float64(x0 + n%width)
+					py := float64(y0 + n/width)
+					pt := supersample(kernel, px, py, &settings)
+					pt.Generation = generation
+					mi.Jobs <- pt
+				}
+			}(w)
+		}
+		wg.Wait()
+	}()
+	return nil
+}
+
+// detailBoost supersamples a square of Settings.DetailRegion pixels around
+// clicked points, for a crisp close-up without anti-aliasing the whole
+// image. The boosted squares are outlined faintly until the next render.
+type detailBoost struct {
+	Active  bool
+	regions []sdl.Rect
+}
+
+func (d *detailBoost) Toggle() {
+	d.Active = !d.Active
+}
+
+// Clear forgets the boosted regions, whose pixels a new render replaces.
+func (d *detailBoost) Clear() {
+	d.regions = d.regions[:0]
+}
+
+// Boost refines the region around the pixel (px, py).
+func (d *detailBoost) Boost(mi *MandelbrotImage, px, py int) {
+	if mi.Rendering() {
+		log.Info("the render is still running; boost the detail once it has finished")
+		return
+	}
+
+	half := int(mi.Settings.DetailRegion) / 2
+	bounds := mi.Settings.renderBounds()
+	x0, y0 := maxInt(px-half, bounds.Min.X), maxInt(py-half, bounds.Min.Y)
+	x1, y1 := minInt(px+half, bounds.Max.X), minInt(py+half, bounds.Max.Y)
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+
+	if err := mi.RefineRegion(x0, y0, x1, y1); err != nil {
+		log.WithError(err).Error("could not boost the detail")
+		return
+	}
+	d.regions = append(d.regions, sdl.Rect{X: int32(x0), Y: int32(y0), W: int32(x1 - x0), H: int32(y1 - y0)})
+}
+
+// Draw outlines the boosted regions.
+func (d *detailBoost) Draw(renderer *sdl.Renderer) error {
+	if len(d.regions) == 0 {
+		return nil
+	}
+
+	err := renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	renderer.SetDrawColor(255, 255, 255, 48)
+	return renderer.DrawRects(d.regions)
+}
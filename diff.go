@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// imageDiff summarises the per-pixel differences between two images.
+type imageDiff struct {
+	Image    *image.RGBA
+	Changed  int
+	MaxDelta int
+}
+
+// diffImages compares a and b channel by channel. Pixels differing by more
+// than tolerance are counted and shown red in the diff image, smaller
+// differences yellow, and identical pixels as a darkened copy of a.
+func diffImages(a, b image.Image, tolerance int) (imageDiff, error) {
+	bounds := a.Bounds()
+	if bounds.Size() != b.Bounds().Size() {
+		return imageDiff{}, errors.Errorf("image sizes differ: %v vs %v", bounds.Size(), b.Bounds().Size())
+	}
+
+	result := imageDiff{Image: image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))}
+	offset := b.Bounds().Min.Sub(bounds.Min)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ca := color.NRGBAModel.Convert(a.At(x, y)).(color.NRGBA)
+			cb := color.NRGBAModel.Convert(b.At(x+offset.X, y+offset.Y)).(color.NRGBA)
+
+			delta := maxInt(
+				maxInt(absInt(int(ca.R)-int(cb.R)), absInt(int(ca.G)-int(cb.G))),
+				maxInt(absInt(int(ca.B)-int(cb.B)), absInt(int(ca.A)-int(cb.A))),
+			)
+			if delta > result.MaxDelta {
+				result.MaxDelta = delta
+			}
+
+			var out color.RGBA
+			switch {
+			case delta > tolerance:
+				result.Changed++
+				out = color.RGBA{R: 255, A: 255}
+			case delta > 0:
+				out = color.RGBA{R: 160, G: 160, A: 255}
+			default:
+				gray := uint8((int(ca.R) + int(ca.G) + int(ca.B)) / 9)
+				out = color.RGBA{R: gray, G: gray, B: gray, A: 255}
+			}
+			result.Image.SetRGBA(x-bounds.Min.X, y-bounds.Min.Y, out)
+		}
+	}
+
+	return result, nil
+}
+
+// runDiff implements the -diff mode: it compares the PNGs named by the
+// first two arguments, writes the diff image to the third, and returns the
+// process exit status, exitDiffers when the images differ beyond
+// tolerance.
+func runDiff(args []string, tolerance int) int {
+	if len(args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: -diff a.png b.png out.png")
+		return exitUsage
+	}
+
+	a, err := readPNG(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeFor(err)
+	}
+	b, err := readPNG(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeFor(err)
+	}
+
+	diff, err := diffImages(a, b, tolerance)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeFor(err)
+	}
+	err = writePNG(args[2], diff.Image)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeFor(err)
+	}
+
+	fmt.Printf("changed pixels: %d\nmax delta: %d\n", diff.Changed, diff.MaxDelta)
+	if diff.Changed > 0 {
+		return exitDiffers
+	}
+	return exitOK
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Exit statuses of the headless modes, for scripts that check them; only
+// -diff uses exitDiffers.
+const (
+	exitOK = 0
+	// exitDiffers: -diff found pixels differing beyond the tolerance.
+	exitDiffers = 1
+	// exitUsage: bad flags, settings or input files.
+	exitUsage = 2
+	// exitIO: a file couldn't be read or written.
+	exitIO = 3
+	// exitCompute: the render itself failed.
+	exitCompute = 4
+)
+
+const exitStatusHelp = `
+Exit status of the headless modes:
+  0  success
+  1  -diff found differing pixels
+  2  usage error: bad flags, settings or input files
+  3  IO error: a file couldn't be read or written
+  4  compute error: the render failed
+`
+
+// exitCodeFor classifies a headless failure. Errors from the filesystem
+// are IO errors; anything else was rejected input.
+func exitCodeFor(err error) int {
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	if errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr) {
+		return exitIO
+	}
+	return exitUsage
+}
+
+// fail reports err on stderr and exits with code.
+func fail(code int, err error, msg string) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+	os.Exit(code)
+}
+
+// runHeadless runs a headless mode, turning a panic during the render into
+// an exitCompute failure instead of a stack trace.
+func runHeadless(run func() error) (code int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			code, err = exitCompute, errors.Errorf("render failed: %v", r)
+		}
+	}()
+
+	if err := run(); err != nil {
+		return exitCodeFor(err), err
+	}
+	return exitOK, nil
+}
+
+// finishHeadless runs a headless mode, calls cleanup and exits with the
+// mode's status, explaining a failure with msg.
+func finishHeadless(msg string, cleanup func(), run func() error) {
+	code, err := runHeadless(run)
+	cleanup()
+	if err != nil {
+		fail(code, err, msg)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// TestExitCodeFor checks that filesystem errors, however wrapped, are IO
+// errors and everything else a usage error.
+func TestExitCodeFor(t *testing.T) {
+	_, openErr := os.Open(filepath.Join(t.TempDir(), "missing.png"))
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "path error", err: openErr, want: exitIO},
+		{name: "wrapped path error", err: errors.Wrap(openErr, "could not open image"), want: exitIO},
+		{name: "link error", err: &os.LinkError{Op: "rename", Err: os.ErrExist}, want: exitIO},
+		{name: "syscall error", err: os.NewSyscallError("write", os.ErrClosed), want: exitIO},
+		{name: "bad input", err: errors.New("unknown palette"), want: exitUsage},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("%s: exit status %d; want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestRunHeadless checks the status of a headless mode that succeeds,
+// fails, and panics.
+func TestRunHeadless(t *testing.T) {
+	if code, err := runHeadless(func() error { return nil }); code != exitOK || err != nil {
+		t.Errorf("success gave %d, %v", code, err)
+	}
+	if code, err := runHeadless(func() error { return errors.New("bad -size") }); code != exitUsage || err == nil {
+		t.Errorf("a usage error gave %d, %v", code, err)
+	}
+	code, err := runHeadless(func() error {
+		var pixels []byte
+		pixels[3] = 1
+		return nil
+	})
+	if code != exitCompute || err == nil {
+		t.Errorf("a panic gave %d, %v", code, err)
+	}
+}
+
+// TestRunDiffStatus runs -diff over every way it can end.
+func TestRunDiffStatus(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, c color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		for i := 0; i < 16; i++ {
+			img.SetRGBA(i%4, i/4, c)
+		}
+		path := filepath.Join(dir, name)
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	red := write("red.png", color.RGBA{R: 255, A: 255})
+	alsoRed := write("also-red.png", color.RGBA{R: 255, A: 255})
+	blue := write("blue.png", color.RGBA{B: 255, A: 255})
+	text := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(text, []byte("not a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "diff.png")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "same", args: []string{red, alsoRed, out}, want: exitOK},
+		{name: "different", args: []string{red, blue, out}, want: exitDiffers},
+		{name: "too few arguments", args: []string{red, blue}, want: exitUsage},
+		{name: "missing input", args: []string{red, filepath.Join(dir, "missing.png"), out}, want: exitIO},
+		{name: "not a PNG", args: []string{text, red, out}, want: exitUsage},
+		{name: "unwritable output", args: []string{red, blue, filepath.Join(dir, "no", "such", "dir.png")}, want: exitIO},
+	}
+	for _, tt := range tests {
+		if got := runDiff(tt.args, 0); got != tt.want {
+			t.Errorf("%s: exit status %d; want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// mainEnv, set in a test binary's environment, makes TestRunMain run main
+// with the arguments after "--" rather than checking anything.
+const mainEnv = "GOMANDELBROTSDL2_RUN_MAIN"
+
+// TestRunMain is a stand-in process for TestExitStatus rather than a test,
+// so that main can exit as it would from a shell.
+func TestRunMain(t *testing.T) {
+	if os.Getenv(mainEnv) != "1" {
+		t.Skip("only runs as a child of TestExitStatus")
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	os.Args = append([]string{"gomandelbrotsdl2"}, args[1:]...)
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	main()
+	t.Fatal("main returned instead of exiting")
+}
+
+// runMain runs main with args in a child process, with config in place of
+// the user's config directory, returning its exit status and stderr.
+func runMain(t *testing.T, config string, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestRunMain$", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), mainEnv+"=1", "XDG_CONFIG_HOME="+config, "HOME="+config)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return exit.ExitCode(), stderr.String()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return 0, stderr.String()
+}
+
+// TestExitStatus runs the headless modes in a child process to the end of
+// every documented exit status, checking the status and what was said on
+// stderr.
+func TestExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	red := filepath.Join(dir, "red.png")
+	blue := filepath.Join(dir, "blue.png")
+	for path, c := range map[string]color.RGBA{red: {R: 255, A: 255}, blue: {B: 255, A: 255}} {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		for i := 0; i < 16; i++ {
+			img.SetRGBA(i%4, i/4, c)
+		}
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "no", "such", "dir")
+	badConfig := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(badConfig, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	view := testSettings(16, 16)
+	if err := writeSidecar(filepath.Join(dir, "view.png"), &view); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "success", args: []string{"-palette-strip", filepath.Join(dir, "strip.png")}, code: exitOK},
+		{name: "same images", args: []string{"-diff", red, red, filepath.Join(dir, "same.png")}, code: exitOK},
+		{name: "differing images", args: []string{"-diff", red, blue, filepath.Join(dir, "diff.png")}, code: exitDiffers},
+		{name: "bad flag value", args: []string{"-render-width", "0", "-palette-strip", filepath.Join(dir, "strip.png")},
+			code: exitUsage, stderr: "the render size must be at least 1x1: got 0x800\n"},
+		{name: "bad smooth blend", args: []string{"-smooth-blend", "1.5", "-palette-strip", filepath.Join(dir, "strip.png")},
+			code: exitUsage, stderr: "the smooth blend must be between 0 and 1: got 1.5\n"},
+		{name: "bad palette", args: []string{"-palette", "nosuch", "-palette-strip", filepath.Join(dir, "strip.png")},
+			code: exitUsage, stderr: "invalid palette: "},
+		{name: "bad config", args: []string{"-config", badConfig, "-palette-strip", filepath.Join(dir, "strip.png")},
+			code: exitUsage, stderr: "could not read the config: could not parse " + badConfig},
+		{name: "palette file", args: []string{"-palette-file", filepath.Join("testdata", "sunset.ggr"), "-palette-strip", filepath.Join(dir, "sunset.png")}, code: exitOK},
+		{name: "missing palette file", args: []string{"-palette-file", filepath.Join(missing, "sunset.ggr"), "-palette-strip", filepath.Join(dir, "strip.png")},
+			code: exitIO, stderr: "could not load the palette file: could not read the palette: open " + filepath.Join(missing, "sunset.ggr")},
+		{name: "diff usage", args: []string{"-diff", red, blue}, code: exitUsage, stderr: "usage: -diff a.png b.png out.png\n"},
+		{name: "unwritable output", args: []string{"-palette-strip", filepath.Join(missing, "strip.png")},
+			code: exitIO, stderr: "could not export the palette: could not create image file: open " + filepath.Join(missing, "strip.png")},
+		{name: "unwritable statistics", args: []string{"-stats", filepath.Join(missing, "stats.json"), "-histogram", filepath.Join(dir, "h.csv")},
+			code: exitIO, stderr: "could not open the statistics file: could not create statistics file: open " + filepath.Join(missing, "stats.json")},
+		{name: "loaded sidecar", args: []string{"-load", sidecarPath(filepath.Join(dir, "view.png")), "-histogram", filepath.Join(dir, "loaded.csv")}, code: exitOK},
+		{name: "missing sidecar", args: []string{"-load", filepath.Join(missing, "view.png.json"), "-histogram", filepath.Join(dir, "h.csv")},
+			code: exitIO, stderr: "could not load the sidecar: could not read the sidecar: open " + filepath.Join(missing, "view.png.json")},
+		{name: "failed render", args: []string{"-render-width", "100000000", "-render-height", "100000000", "-histogram", filepath.Join(dir, "h.csv")},
+			code: exitCompute, stderr: "could not write the histogram: render failed: runtime error: makeslice: len out of range\n"},
+	}
+	for _, tt := range tests {
+		code, stderr := runMain(t, dir, tt.args...)
+		if code != tt.code {
+			t.Errorf("%s: exit status %d; want %d (stderr %q)", tt.name, code, tt.code, stderr)
+		}
+		if tt.stderr == "" && stderr != "" {
+			t.Errorf("%s: said %q on stderr; want nothing", tt.name, stderr)
+		}
+		if !strings.HasPrefix(stderr, tt.stderr) {
+			t.Errorf("%s: said %q on stderr; want %q", tt.name, stderr, tt.stderr)
+		}
+	}
+}
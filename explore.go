@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// maxInteriorFraction is the share of a window that may sit inside the set
+// before the window is skipped as solid interior.
+const maxInteriorFraction = 0.5
+
+// MostDetailedRegion scans the cached iteration counts in square windows of
+// Settings.VarianceWindow pixels and returns the centre of the window with
+// the highest iteration variance. ok is false when no window has any detail
+// outside the set's interior.
+func (mi *MandelbrotImage) MostDetailedRegion() (px, py float64, ok bool) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	width := int64(mi.Width)
+	height := int64(mi.Height)
+	size := mi.Settings.VarianceWindow
+	if size <= 0 || size > width || size > height {
+		size = minInt64(width, height)
+	}
+	maxIters := mi.Settings.MaxIterations
+
+	best := 0.0
+	var x, y int64
+	for y = 0; y+size <= height; y += size {
+		for x = 0; x+size <= width; x += size {
+			var sum, sumSq float64
+			var interior int64
+			var i, j int64
+			for j = y; j < y+size; j++ {
+				for i = x; i < x+size; i++ {
+					iters := mi.Iterations[j*width+i]
+					if iters >= maxIters {
+						interior++
+					}
+					v := float64(iters)
+					sum += v
+					sumSq += v * v
+				}
+			}
+
+			n := float64(size * size)
+			if float64(interior)/n > maxInteriorFraction {
+				continue
+			}
+
+			mean := sum / n
+			variance := sumSq/n - mean*mean
+			if variance > best {
+				best = variance
+				px = float64(x) + float64(size)/2
+				py = float64(y) + float64(size)/2
+				ok = true
+			}
+		}
+	}
+
+	return px, py, ok
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+const (
+	// randomViewCandidates is how many boundary locations are compared when
+	// picking a random view.
+	randomViewCandidates = 64
+	// randomViewProbe is the side of the grid sampled around a candidate.
+	randomViewProbe = 8
+)
+
+// RandomView picks a pseudo-random location near the boundary of the set at
+// a random depth. Candidates whose neighbourhood is all interior or all
+// exterior are rejected and the one with the most iteration variance wins.
+func RandomView(rng *rand.Rand, settings *Settings, kernel Kernel) (re, im, span float64, ok bool) {
+	best := 0.0
+	for n := 0; n < randomViewCandidates; n++ {
+		cre := -2.5 + rng.Float64()*3.5
+		cim := -1.25 + rng.Float64()*2.5
+		cspan := math.Pow(10, -1-rng.Float64()*4)
+
+		var sum, sumSq float64
+		var interior int
+		for j := 0; j < randomViewProbe; j++ {
+			for i := 0; i < randomViewProbe; i++ {
+				x := cre + (float64(i)/(randomViewProbe-1)-0.5)*cspan
+				y := cim + (float64(j)/(randomViewProbe-1)-0.5)*cspan
+				iters, _, escaped := kernel.Iterate(seed(x, y, settings))
+				if !escaped {
+					interior++
+				}
+				v := float64(iters)
+				sum += v
+				sumSq += v * v
+			}
+		}
+
+		samples := float64(randomViewProbe * randomViewProbe)
+		if interior == 0 || float64(interior) == samples {
+			continue
+		}
+
+		mean := sum / samples
+		if variance := sumSq/samples - mean*mean; variance > best {
+			best = variance
+			re, im, span = cre, cim, cspan
+			ok = true
+		}
+	}
+
+	return re, im, span, ok
+}
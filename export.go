@@ -0,0 +1,213 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open image")
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", path)
+	}
+	return img, nil
+}
+
+// displayColor returns the color a point shows on screen. The texture is
+// ARGB8888, so the bytes DrawPoint stores as red, green, blue are read back
+// as blue, green, red. Transparent points come out as transparent black,
+// since color.RGBA is premultiplied.
+func displayColor(point Point) color.RGBA {
+	if point.Transparent {
+		return color.RGBA{}
+	}
+	return color.RGBA{R: point.Blue, G: point.Green, B: point.Red, A: 255}
+}
+
+// deepColor is displayColor at 16 bits per channel, for red, green and
+// blue intensities on the 0-255 scale. There is no dithering; 16 bits
+// don't band.
+func deepColor(red, green, blue float64, iters int64, settings *Settings) color.NRGBA64 {
+	if settings.AlphaMode.Transparent(iters, settings.MaxIterations) {
+		return color.NRGBA64{}
+	}
+	channel := func(v float64) uint16 {
+		v = encodeChannel(v, settings) / 255
+		return uint16(math.Round(math.Max(0, math.Min(v, 1)) * 0xffff))
+	}
+	return color.NRGBA64{R: channel(blue), G: channel(green), B: channel(red), A: 0xffff}
+}
+
+// paletteStrip renders the palette as a horizontal gradient, t running from
+// 0 at the left edge to 1 at the right, at 16 bits per channel with deep
+// set.
+func paletteStrip(settings *Settings, width, height int, deep bool) image.Image {
+	var img *image.RGBA
+	var deepImg *image.NRGBA64
+	if deep {
+		deepImg = image.NewNRGBA64(image.Rect(0, 0, width, height))
+	} else {
+		img = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	// the strip shows the palette whatever the alpha mode
+	opaque := settings.Clone()
+	opaque.AlphaMode = AlphaOpaque
+
+	for x := 0; x < width; x++ {
+		t := float64(x) / float64(maxInt(width-1, 1))
+		red, green, blue := colorAt(t, settings)
+		for y := 0; y < height; y++ {
+			if deep {
+				deepImg.SetNRGBA64(x, y, deepColor(red, green, blue, 0, &opaque))
+			} else {
+				img.SetRGBA(x, y, displayColor(pixelPoint(float64(x), float64(y), red, green, blue, 0, &opaque)))
+			}
+		}
+	}
+	if deep {
+		return deepImg
+	}
+	return img
+}
+
+// exportOptions picks the encoder for exported images. An empty Format
+// goes by the file extension; Quality is for JPEG, from 1 to 100. BitDepth
+// is 8 or, for PNG only, 16 bits per channel.
+type exportOptions struct {
+	Format   string
+	Quality  int
+	BitDepth int
+}
+
+// imageFormat is the format, png, jpeg or bmp, to write path in.
+func (o exportOptions) imageFormat(path string) (string, error) {
+	format := strings.ToLower(o.Format)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	switch format {
+	case "png":
+		return "png", nil
+	case "jpg", "jpeg":
+		return "jpeg", nil
+	case "bmp":
+		return "bmp", nil
+	}
+	return "", errors.Errorf("unsupported image format %q for %s; use png, jpeg or bmp", format, path)
+}
+
+// Ext is the file extension for the chosen format, defaulting to png.
+func (o exportOptions) Ext() string {
+	switch strings.ToLower(o.Format) {
+	case "jpg", "jpeg":
+		return ".jpg"
+	case "bmp":
+		return ".bmp"
+	}
+	return ".png"
+}
+
+// writeImage writes img to path in the format the options pick.
+func writeImage(path string, img image.Image, opts exportOptions) error {
+	format, err := opts.imageFormat(path)
+	if err != nil {
+		return err
+	}
+	if format == "png" {
+		return writePNG(path, img)
+	}
+	if opts.BitDepth == 16 {
+		return errors.Errorf("16-bit output needs png, not %s, for %s", format, path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "could not create image file")
+	}
+
+	if format == "jpeg" {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: opts.Quality})
+	} else {
+		err = encodeBMP(f, img)
+	}
+	if err != nil {
+		f.Close()
+		return errors.Wrapf(err, "could not encode %s", path)
+	}
+	return errors.Wrapf(f.Close(), "could not write %s", path)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "could not create image file")
+	}
+
+	err = png.Encode(f, img)
+	if err != nil {
+		f.Close()
+		return errors.Wrapf(err, "could not encode %s", path)
+	}
+	return errors.Wrapf(f.Close(), "could not write %s", path)
+}
+
+// SavePNG writes the Snapshot of the frame to path as a PNG.
+func (mi *MandelbrotImage) SavePNG(path string) error {
+	return writePNG(path, mi.Snapshot())
+}
+
+// savedPNG reports how a PNG saved in the background went.
+type savedPNG struct {
+	Path string
+	Err  error
+}
+
+// SavePNGAsync takes the Snapshot of the frame before returning, so later
+// renders can't change what is saved, then writes it to path as a PNG on
+// another goroutine and reports on done. The sidecar of settings goes
+// alongside unless settings is nil.
+func (mi *MandelbrotImage) SavePNGAsync(path string, settings *Settings, done chan<- savedPNG) {
+	img := mi.Snapshot()
+	var car *Settings
+	if settings != nil {
+		clone := settings.Clone()
+		car = &clone
+	}
+	go func() {
+		err := writePNG(path, img)
+		if err == nil && car != nil {
+			err = writeSidecar(path, car)
+		}
+		done <- savedPNG{Path: path, Err: err}
+	}()
+}
+
+// Notice is the text shown once the save is done.
+func (s savedPNG) Notice() string {
+	if s.Err != nil {
+		return "save failed"
+	}
+	return "saved " + filepath.Base(s.Path)
+}
+
+// savePath names a saved frame in dir after the time it was saved, to the
+// millisecond so quick saves don't overwrite each other.
+func savePath(dir string, t time.Time) string {
+	return filepath.Join(dir, "mandelbrot-"+t.Format("20060102-150405.000")+".png")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSavePNG saves a frame of known pixels and reads it back, checking
+// that the texture's blue, green, red bytes land in the file as red, green,
+// blue, that cleared pixels save as transparent black, and that the
+// contours the window draws are saved too.
+func TestSavePNG(t *testing.T) {
+	settings := testSettings(3, 2)
+	mi := startImage(&settings)
+	finish(mi)
+
+	// blue, green, red, alpha as the texture holds them
+	mi.Pixels = []byte{
+		10, 20, 30, 255, 200, 100, 50, 255, 0, 0, 0, 255,
+		1, 2, 3, 0, 255, 255, 255, 255, 7, 8, 9, 255,
+	}
+	mi.Iterations = []int64{5, 5, 5, 5, 5, 5}
+	want := []color.RGBA{
+		{R: 30, G: 20, B: 10, A: 255}, {R: 50, G: 100, B: 200, A: 255}, {A: 255},
+		{}, {R: 255, G: 255, B: 255, A: 255}, {R: 9, G: 8, B: 7, A: 255},
+	}
+	check := func(name string) {
+		t.Helper()
+		path := savePath(t.TempDir(), time.Now())
+		if err := mi.SavePNG(path); err != nil {
+			t.Fatal(err)
+		}
+		img, err := readPNG(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != image.Rect(0, 0, 3, 2) {
+			t.Fatalf("%s: the saved frame is %v; want 3x2", name, img.Bounds())
+		}
+		for i, w := range want {
+			if got := color.RGBAModel.Convert(img.At(i%3, i/3)); got != w {
+				t.Errorf("%s: pixel (%d, %d) saved as %v; want %v", name, i%3, i/3, got, w)
+			}
+		}
+	}
+	check("plain")
+
+	// a contour between the middle and right columns whitens the middle
+	mi.Iterations = []int64{5, 5, 25, 5, 5, 25}
+	settings.Contours = true
+	want[1] = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	check("contoured")
+
+	if err := mi.SavePNG(filepath.Join(t.TempDir(), "missing", "frame.png")); err == nil {
+		t.Error("saving into a missing directory succeeded")
+	}
+}
+
+// TestSavePNGAsync checks that a background save writes the frame as it
+// was when the save began, with its sidecar, and reports failures.
+func TestSavePNGAsync(t *testing.T) {
+	settings := testSettings(3, 2)
+	mi := startImage(&settings)
+	finish(mi)
+	mi.Pixels = []byte{
+		10, 20, 30, 255, 200, 100, 50, 255, 0, 0, 0, 255,
+		1, 2, 3, 255, 255, 255, 255, 255, 7, 8, 9, 255,
+	}
+	mi.Iterations = []int64{5, 5, 5, 5, 5, 5}
+	want := mi.Snapshot()
+
+	done := make(chan savedPNG)
+	path := savePath(t.TempDir(), time.Now())
+	mi.SavePNGAsync(path, &settings, done)
+	// a later render mustn't reach the saved frame
+	mi.mu.Lock()
+	for i := range mi.Pixels {
+		mi.Pixels[i] = 99
+	}
+	mi.mu.Unlock()
+	settings.Palette = "gold"
+
+	saved := <-done
+	if saved.Err != nil || saved.Path != path {
+		t.Fatalf("the save reported %+v", saved)
+	}
+	if got := saved.Notice(); got != "saved "+filepath.Base(path) {
+		t.Errorf("the notice is %q", got)
+	}
+	img, err := readPNG(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if got, w := color.RGBAModel.Convert(img.At(i%3, i/3)), want.RGBAAt(i%3, i/3); got != w {
+			t.Errorf("pixel (%d, %d) saved as %v; want %v", i%3, i/3, got, w)
+		}
+	}
+	loaded, err := readSidecar(sidecarPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Palette != palettes[0].Name {
+		t.Errorf("the sidecar has palette %q; want the one at the time of the save", loaded.Palette)
+	}
+
+	path = savePath(t.TempDir(), time.Now())
+	mi.SavePNGAsync(path, nil, done)
+	if saved := <-done; saved.Err != nil {
+		t.Fatal(saved.Err)
+	}
+	if _, err := os.Stat(sidecarPath(path)); !os.IsNotExist(err) {
+		t.Errorf("a save without settings wrote a sidecar: %v", err)
+	}
+
+	mi.SavePNGAsync(filepath.Join(t.TempDir(), "missing", "frame.png"), &settings, done)
+	if saved := <-done; saved.Err == nil || saved.Notice() != "save failed" {
+		t.Errorf("saving into a missing directory reported %+v, %q", saved, saved.Notice())
+	}
+}
+
+// TestSavePath checks that saves are named after the time to the
+// millisecond, so saves a millisecond apart don't collide.
+func TestSavePath(t *testing.T) {
+	at := time.Date(2026, 3, 7, 9, 5, 2, 40*int(time.Millisecond), time.UTC)
+	if got, want := savePath("shots", at), filepath.Join("shots", "mandelbrot-20260307-090502.040.png"); got != want {
+		t.Errorf("savePath is %q; want %q", got, want)
+	}
+	if savePath("", at) == savePath("", at.Add(time.Millisecond)) {
+		t.Error("saves a millisecond apart have the same name")
+	}
+	if name := savePath("", at); !strings.HasSuffix(name, ".png") || strings.ContainsAny(name, " :") {
+		t.Errorf("%q isn't a portable PNG file name", name)
+	}
+}
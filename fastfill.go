@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fastFillGrid is how many samples a side uniformFill probes.
+const fastFillGrid = 32
+
+// uniformFill samples a sparse grid over the view and, if every sample
+// has the same iteration count, as deep in the main cardioid or far
+// outside the set, fills the whole image with it and reports true. A
+// feature smaller than the grid spacing can be missed. Only the region of
+// interest is probed and filled.
+func (mi *MandelbrotImage) uniformFill(settings *Settings, kernel Kernel, colors *colorTable) bool {
+	bounds := settings.renderBounds()
+	x0, y0 := float64(bounds.Min.X), float64(bounds.Min.Y)
+	w, h := float64(bounds.Dx()-1), float64(bounds.Dy()-1)
+	first := samplePixel(kernel, x0, y0, settings)
+	var gx, gy int64
+	for gy = 0; gy <= fastFillGrid; gy++ {
+		for gx = 0; gx <= fastFillGrid; gx++ {
+			px := x0 + math.Round(float64(gx)*w/fastFillGrid)
+			py := y0 + math.Round(float64(gy)*h/fastFillGrid)
+			if samplePixel(kernel, px, py, settings) != first {
+				return false
+			}
+		}
+	}
+
+	mi.Cancel()
+	red, green, blue := colors.Color(first)
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	width := int(mi.Width)
+	for idx := range mi.Iterations {
+		if !settings.inBounds(idx%width, idx/width) {
+			continue
+		}
+		mi.drawPoint(pixelPoint(float64(idx%width), float64(idx/width), red, green, blue, first, settings))
+	}
+	log.WithField("iterations", first).Debug("view is uniform; filled without a full render")
+	return true
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// fixed is a signed 64-bit fixed-point number with fixedFrac fractional
+// bits: a range of +-128 at a resolution of about 1.4e-17. Integer math
+// rounds the same way on every platform, so renders with it are
+// bit-identical everywhere.
+type fixed int64
+
+const (
+	fixedFrac = 56
+	fixedOne  = fixed(1) << fixedFrac
+
+	// fixedLimit bounds the orbit so its products stay in range; an orbit
+	// that reaches it is diverging and counts as escaped.
+	fixedLimit = 4 * fixedOne
+)
+
+func toFixed(v float64) fixed {
+	return fixed(math.Round(math.Ldexp(v, fixedFrac)))
+}
+
+func (f fixed) Float() float64 {
+	return math.Ldexp(float64(f), -fixedFrac)
+}
+
+func (f fixed) abs() uint64 {
+	if f < 0 {
+		return uint64(-f)
+	}
+	return uint64(f)
+}
+
+// mul multiplies two fixed-point numbers, rounding to nearest. The product
+// must fit in the range.
+func (f fixed) mul(g fixed) fixed {
+	hi, lo := bits.Mul64(f.abs(), g.abs())
+	lo, carry := bits.Add64(lo, 1<<(fixedFrac-1), 0)
+	hi += carry
+
+	product := fixed(hi<<(64-fixedFrac) | lo>>fixedFrac)
+	if (f < 0) != (g < 0) {
+		return -product
+	}
+	return product
+}
+
+func fixedComplex(x, y fixed) complex128 {
+	return complex(x.Float(), y.Float())
+}
+
+// fixedEscaped is escapedBy for fixed-point orbits.
+func fixedEscaped(x, y fixed, components bool) bool {
+	if x >= fixedLimit || x <= -fixedLimit || y >= fixedLimit || y <= -fixedLimit {
+		return true
+	}
+	if components {
+		return x > 2*fixedOne || x < -2*fixedOne || y > 2*fixedOne || y < -2*fixedOne
+	}
+	return x.mul(x)+y.mul(y) > 4*fixedOne
+}
+
+// fixedKernel iterates z = z^2 + c like mandelbrotKernel, in fixed-point
+// rather than float64.
+type fixedKernel struct {
+	maxIterations int64
+	detectPeriod  bool
+	components    bool
+	bailout       float64
+}
+
+func newFixedKernel(settings *Settings) (Kernel, error) {
+	return fixedKernel{
+		maxIterations: settings.MaxIterations,
+		detectPeriod:  settings.PeriodDetection,
+		components:    settings.ComponentBailout,
+		bailout:       bailoutRadius(settings),
+	}, nil
+}
+
+func (k fixedKernel) Iterate(c, z complex128) (int, complex128, bool) {
+	if math.Abs(real(c)) >= fixedLimit.Float() || math.Abs(imag(c)) >= fixedLimit.Float() ||
+		math.Abs(real(z)) >= fixedLimit.Float() || math.Abs(imag(z)) >= fixedLimit.Float() {
+		if k.bailout > bailout {
+			return k.finish(0, 0, c, z*z+c)
+		}
+		return 0, z, true
+	}
+
+	cx, cy := toFixed(real(c)), toFixed(imag(c))
+	x, y := toFixed(real(z)), toFixed(imag(z))
+	period := newPeriodChecker(z)
+
+	var iters int
+	var i int64
+	for i = 0; i < k.maxIterations; i++ {
+		x, y = x.mul(x)-y.mul(y)+cx, 2*x.mul(y)+cy
+		if fixedEscaped(x, y, k.components) {
+			if k.bailout > bailout {
+				return k.finish(iters, i, c, fixedComplex(x, y))
+			}
+			return iters, fixedComplex(x, y), true
+		}
+		iters++
+
+		if k.detectPeriod && period.Periodic(fixedComplex(x, y)) {
+			return int(k.maxIterations), fixedComplex(x, y), false
+		}
+	}
+	return iters, fixedComplex(x, y), false
+}
+
+// finish follows an orbit that escaped at iteration i on out to the smooth
+// coloring radius, which is beyond the fixed-point range, in float64; that
+// only moves the colors, not the set.
+func (k fixedKernel) finish(iters int, i int64, c, z complex128) (int, complex128, bool) {
+	for !escapedBy(z, k.components, k.bailout) {
+		iters++
+		if i++; i == k.maxIterations {
+			return iters, z, false
+		}
+		z = z*z + c
+	}
+	return iters, z, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// failingText is a text renderer that can't draw anything.
+type failingText struct {
+	calls *int
+}
+
+func (f failingText) Draw(renderer *sdl.Renderer, x, y, scale int32, text string) error {
+	*f.calls++
+	return errors.New("no font")
+}
+
+// TestTextFallback checks that a text renderer that fails is swapped for
+// noText after its first failure, and that drawing then carries on without
+// error or a renderer to draw on.
+func TestTextFallback(t *testing.T) {
+	previous := overlayText
+	defer func() { overlayText = previous }()
+
+	calls := 0
+	overlayText = failingText{calls: &calls}
+	for i := 0; i < 3; i++ {
+		if err := drawText(nil, 0, 0, 1, "period 3"); err != nil {
+			t.Errorf("drawText reported %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("the failing renderer was tried %d times; want once", calls)
+	}
+	if _, ok := overlayText.(noText); !ok {
+		t.Errorf("the overlays fell back to %T; want noText", overlayText)
+	}
+
+	if err := (noText{}).Draw(nil, 10, 10, 2, "anything at all"); err != nil {
+		t.Errorf("noText reported %v", err)
+	}
+	// blank text has nothing to fill, so even the bitmap font leaves the
+	// renderer alone
+	if err := (bitmapText{}).Draw(nil, 0, 0, 1, "   "); err != nil {
+		t.Errorf("blank bitmap text reported %v", err)
+	}
+}
+
+// TestDrawImageText draws a glyph into an image and checks it against the
+// font bitmap, with unknown characters drawn as '?'.
+func TestDrawImageText(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	for _, text := range []string{"a", "~"} {
+		img := image.NewRGBA(image.Rect(0, 0, glyphWidth*2, glyphHeight*2))
+		drawImageText(img, 0, 0, 2, text, white)
+
+		glyph := glyphs['A']
+		if text == "~" {
+			glyph = glyphs['?']
+		}
+		for y := 0; y < glyphHeight*2; y++ {
+			for x := 0; x < glyphWidth*2; x++ {
+				lit := glyph[y/2]&(1<<uint(glyphWidth-1-x/2)) != 0
+				if got := img.RGBAAt(x, y) == white; got != lit {
+					t.Fatalf("%q: pixel (%d, %d) lit %v; want %v", text, x, y, got, lit)
+				}
+			}
+		}
+	}
+	if w := textWidth("abc", 2); w != 3*(glyphWidth+1)*2 {
+		t.Errorf("three characters at scale 2 are %d wide", w)
+	}
+}
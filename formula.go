@@ -0,0 +1,365 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// formulaFunc computes the next z of a user-supplied iteration from the
+// current z and the parameter c.
+type formulaFunc func(z, c complex128) complex128
+
+// maxIntegerPower bounds the exponents evaluated by repeated squaring
+// rather than cmplx.Pow.
+const maxIntegerPower = 64
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value complex128
+	pos   int
+}
+
+func (t token) String() string {
+	at := " at position " + strconv.Itoa(t.pos+1)
+	if t.kind == tokenEOF {
+		return "end of formula" + at
+	}
+	return strconv.Quote(t.text) + at
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+
+			text := string(runes[start:i])
+			v, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, errors.Errorf("bad number %q at position %d", text, start+1)
+			}
+
+			value := complex(v, 0)
+			// a trailing i makes an imaginary literal, as in 0.5i
+			if i < len(runes) && runes[i] == 'i' && (i+1 == len(runes) || !unicode.IsLetter(runes[i+1])) {
+				value = complex(0, v)
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), value: value, pos: start})
+
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+
+		case strings.ContainsRune("+-*/^()", r):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: i})
+			i++
+
+		default:
+			return nil, errors.Errorf("unexpected character %q at position %d", r, i+1)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// formulaNode is a compiled sub-expression. Constant sub-expressions are
+// folded at parse time so they cost nothing per iteration.
+type formulaNode struct {
+	eval     formulaFunc
+	constant bool
+	value    complex128
+}
+
+func constantNode(v complex128) formulaNode {
+	return formulaNode{
+		eval:     func(z, c complex128) complex128 { return v },
+		constant: true,
+		value:    v,
+	}
+}
+
+// formulaParser is a recursive descent parser over the grammar
+//
+//	expr  = term { ("+" | "-") term }
+//	term  = unary { ("*" | "/") unary }
+//	unary = ("+" | "-") unary | power
+//	power = atom [ "^" unary ]
+//	atom  = number | "z" | "c" | "i" | "(" expr ")"
+type formulaParser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseFormula compiles an iteration formula over complex z and c, such as
+// "z*z + c" or "z^3 - 0.5i*z + c".
+func ParseFormula(src string) (formulaFunc, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid formula %q", src)
+	}
+
+	p := &formulaParser{tokens: tokens}
+	node, err := p.expr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = errors.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid formula %q", src)
+	}
+
+	return node.eval, nil
+}
+
+func (p *formulaParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *formulaParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *formulaParser) acceptOperator(ops string) (string, bool) {
+	t := p.peek()
+	if t.kind == tokenOperator && strings.Contains(ops, t.text) {
+		p.pos++
+		return t.text, true
+	}
+	return "", false
+}
+
+func (p *formulaParser) expr() (formulaNode, error) {
+	left, err := p.term()
+	if err != nil {
+		return left, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("+-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return right, err
+		}
+		left = binaryNode(op, left, right)
+	}
+}
+
+func (p *formulaParser) term() (formulaNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return left, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("*/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return right, err
+		}
+		left = binaryNode(op, left, right)
+	}
+}
+
+func (p *formulaParser) unary() (formulaNode, error) {
+	op, ok := p.acceptOperator("+-")
+	if !ok {
+		return p.power()
+	}
+
+	operand, err := p.unary()
+	if err != nil || op == "+" {
+		return operand, err
+	}
+	if operand.constant {
+		return constantNode(-operand.value), nil
+	}
+	eval := operand.eval
+	return formulaNode{eval: func(z, c complex128) complex128 { return -eval(z, c) }}, nil
+}
+
+func (p *formulaParser) power() (formulaNode, error) {
+	base, err := p.atom()
+	if err != nil {
+		return base, err
+	}
+	if _, ok := p.acceptOperator("^"); !ok {
+		return base, nil
+	}
+
+	exponent, err := p.unary()
+	if err != nil {
+		return exponent, err
+	}
+	return binaryNode("^", base, exponent), nil
+}
+
+func (p *formulaParser) atom() (formulaNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return constantNode(t.value), nil
+
+	case tokenIdent:
+		switch t.text {
+		case "z":
+			return formulaNode{eval: func(z, c complex128) complex128 { return z }}, nil
+		case "c":
+			return formulaNode{eval: func(z, c complex128) complex128 { return c }}, nil
+		case "i":
+			return constantNode(1i), nil
+		}
+		return formulaNode{}, errors.Errorf("unknown variable %s", t)
+
+	case tokenOperator:
+		if t.text == "(" {
+			node, err := p.expr()
+			if err != nil {
+				return node, err
+			}
+			if _, ok := p.acceptOperator(")"); !ok {
+				return node, errors.Errorf("expected \")\" but found %s", p.peek())
+			}
+			return node, nil
+		}
+	}
+
+	return formulaNode{}, errors.Errorf("unexpected %s", t)
+}
+
+func binaryNode(op string, left, right formulaNode) formulaNode {
+	l, r := left.eval, right.eval
+
+	var eval formulaFunc
+	switch op {
+	case "+":
+		eval = func(z, c complex128) complex128 { return l(z, c) + r(z, c) }
+	case "-":
+		eval = func(z, c complex128) complex128 { return l(z, c) - r(z, c) }
+	case "*":
+		eval = func(z, c complex128) complex128 { return l(z, c) * r(z, c) }
+	case "/":
+		eval = func(z, c complex128) complex128 { return l(z, c) / r(z, c) }
+	case "^":
+		eval = func(z, c complex128) complex128 { return cmplx.Pow(l(z, c), r(z, c)) }
+
+		// small integer exponents are far cheaper by repeated squaring
+		if right.constant && imag(right.value) == 0 {
+			n := real(right.value)
+			if n == math.Trunc(n) && math.Abs(n) <= maxIntegerPower {
+				e := int(n)
+				eval = func(z, c complex128) complex128 { return integerPower(l(z, c), e) }
+			}
+		}
+	}
+
+	if left.constant && right.constant {
+		return constantNode(eval(0, 0))
+	}
+	return formulaNode{eval: eval}
+}
+
+func integerPower(z complex128, n int) complex128 {
+	if n < 0 {
+		return 1 / integerPower(z, -n)
+	}
+
+	result := complex(1, 0)
+	for n > 0 {
+		if n&1 == 1 {
+			result *= z
+		}
+		z *= z
+		n >>= 1
+	}
+	return result
+}
+
+// formulaKernel iterates a user-supplied formula taken from
+// Settings.Formula.
+type formulaKernel struct {
+	step          formulaFunc
+	maxIterations int64
+	detectPeriod  bool
+	components    bool
+	bailout       float64
+}
+
+func newFormulaKernel(settings *Settings) (Kernel, error) {
+	step, err := ParseFormula(settings.Formula)
+	if err != nil {
+		return nil, err
+	}
+	return formulaKernel{
+		step:          step,
+		maxIterations: settings.MaxIterations,
+		detectPeriod:  settings.PeriodDetection,
+		components:    settings.ComponentBailout,
+		bailout:       bailoutRadius(settings),
+	}, nil
+}
+
+func (k formulaKernel) Iterate(c, z complex128) (int, complex128, bool) {
+	period := newPeriodChecker(z)
+
+	var iters int
+	var i int64
+	for i = 0; i < k.maxIterations; i++ {
+		z = k.step(z, c)
+		if escapedBy(z, k.components, k.bailout) {
+			return iters, z, true
+		}
+		iters++
+
+		if k.detectPeriod && period.Periodic(z) {
+			return int(k.maxIterations), z, false
+		}
+	}
+	return iters, z, false
+}
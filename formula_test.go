@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+// TestParseFormula compiles formulas and evaluates them at a few points,
+// covering precedence and associativity, unary minus, the i literal, and
+// ^ taking integerPower for small whole exponents and cmplx.Pow for the
+// rest.
+func TestParseFormula(t *testing.T) {
+	points := []struct{ z, c complex128 }{{0.5, 0}, {1 + 1i, -0.5}, {-0.3 + 0.7i, 0.25 - 1i}}
+	tests := []struct {
+		formula string
+		want    func(z, c complex128) complex128
+	}{
+		{"z*z + c", func(z, c complex128) complex128 { return z*z + c }},
+		{"1 + 2*3", func(z, c complex128) complex128 { return 7 }},
+		{"(1 + 2)*3", func(z, c complex128) complex128 { return 9 }},
+		{"1 - 2 - 3", func(z, c complex128) complex128 { return -4 }},
+		{"12/3/2", func(z, c complex128) complex128 { return 2 }},
+		{"2*3^2", func(z, c complex128) complex128 { return 18 }},
+		{"2^3^2", func(z, c complex128) complex128 { return 512 }},
+		{"-2^2", func(z, c complex128) complex128 { return -4 }},
+		{"--z", func(z, c complex128) complex128 { return z }},
+		{"-z*c", func(z, c complex128) complex128 { return -z * c }},
+		{"z - -c", func(z, c complex128) complex128 { return z + c }},
+		{"+z", func(z, c complex128) complex128 { return z }},
+		{"i*i", func(z, c complex128) complex128 { return -1 }},
+		{"0.5i*z + 2i", func(z, c complex128) complex128 { return 0.5i*z + 2i }},
+		{"1.5e1 + 2E-1i", func(z, c complex128) complex128 { return 15 + 0.2i }},
+		{"z^3 + c", func(z, c complex128) complex128 { return integerPower(z, 3) + c }},
+		{"z^-2 + c", func(z, c complex128) complex128 { return integerPower(z, -2) + c }},
+		{"z^(1+1)", func(z, c complex128) complex128 { return integerPower(z, 2) }},
+		{"z^2.5 + c", func(z, c complex128) complex128 { return cmplx.Pow(z, 2.5) + c }},
+		{"z^65", func(z, c complex128) complex128 { return cmplx.Pow(z, 65) }},
+		{"z^c", func(z, c complex128) complex128 { return cmplx.Pow(z, c) }},
+		{"z^(2i)", func(z, c complex128) complex128 { return cmplx.Pow(z, 2i) }},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormula(tt.formula)
+		if err != nil {
+			t.Errorf("ParseFormula(%q): %v", tt.formula, err)
+			continue
+		}
+		for _, p := range points {
+			got, want := f(p.z, p.c), tt.want(p.z, p.c)
+			if got != want {
+				t.Errorf("%q at z=%v, c=%v gave %v; want %v", tt.formula, p.z, p.c, got, want)
+			}
+		}
+	}
+
+	// the fast path is exact where cmplx.Pow rounds
+	f, _ := ParseFormula("z^3")
+	if got := f(1+1i, 0); got != -2+2i {
+		t.Errorf("(1+i)^3 gave %v; want exactly -2+2i", got)
+	}
+}
+
+// TestParseFormulaErrors checks that bad formulas are rejected with the
+// position of the trouble.
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct {
+		formula string
+		want    string
+	}{
+		{"(z*z + c", `expected ")" but found end of formula at position 9`},
+		{"((z) + c", `expected ")" but found end of formula at position 9`},
+		{"z*z + c)", `unexpected ")" at position 8`},
+		{"(z", `expected ")" but found end of formula at position 3`},
+		{"()", `unexpected ")" at position 2`},
+		{"z*z +", `unexpected end of formula at position 6`},
+		{"z^", `unexpected end of formula at position 3`},
+		{"z*", `unexpected end of formula at position 3`},
+		{"z + * c", `unexpected "*" at position 5`},
+		{"", `unexpected end of formula at position 1`},
+		{"z*x + c", `unknown variable "x" at position 3`},
+		{"zz + c", `unknown variable "zz" at position 1`},
+		{"sin(z)", `unknown variable "sin" at position 1`},
+		{"z c", `unexpected "c" at position 3`},
+		{"z # c", `unexpected character '#' at position 3`},
+		{"1.2.3 + z", `bad number "1.2.3" at position 1`},
+	}
+	for _, tt := range tests {
+		_, err := ParseFormula(tt.formula)
+		if err == nil {
+			t.Errorf("ParseFormula(%q) succeeded", tt.formula)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseFormula(%q) = %q; want it to say %q", tt.formula, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	gridLines = 8
+	gridScale = 1
+)
+
+// gridOverlay draws the real and imaginary axes over the image, with a
+// light grid at round complex values.
+type gridOverlay struct {
+	Visible bool
+}
+
+func (g *gridOverlay) Toggle() {
+	g.Visible = !g.Visible
+}
+
+// gridStep picks a 1, 2 or 5 times power-of-ten spacing that fits about
+// the given number of lines across span.
+func gridStep(span float64, lines int) float64 {
+	raw := span / float64(lines)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*magnitude >= raw {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// gridLabel formats v with just enough decimals to tell lines step apart.
+func gridLabel(v, step float64) string {
+	decimals := int(math.Max(0, -math.Floor(math.Log10(step))))
+	if v == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.*f", decimals, v)
+}
+
+func (g *gridOverlay) Draw(renderer *sdl.Renderer, settings *Settings) error {
+	if !g.Visible {
+		return nil
+	}
+
+	// the bounds of the view, which a rotation turns off the axes
+	reMin, imMin := math.Inf(1), math.Inf(1)
+	reMax, imMax := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {settings.Width, 0}, {0, settings.Height}, {settings.Width, settings.Height}} {
+		re, im := settings.PixelToComplex(corner[0], corner[1])
+		reMin, reMax = math.Min(reMin, re), math.Max(reMax, re)
+		imMin, imMax = math.Min(imMin, im), math.Max(imMax, im)
+	}
+	step := gridStep(math.Max(reMax-reMin, imMax-imMin), gridLines)
+	reMid, imMid := settings.ViewCenter()
+
+	err := renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	// line draws the grid line from (re0, im0) to (re1, im1) and labels it
+	// at the image edge, or where it crosses the middle of a rotated view
+	line := func(re0, im0, re1, im1, labelRe, labelIm float64, label string, axis bool) error {
+		x0, y0 := settings.ComplexToPixel(re0, im0)
+		x1, y1 := settings.ComplexToPixel(re1, im1)
+		lx, ly := settings.ComplexToPixel(labelRe, labelIm)
+
+		setGridColor(renderer, axis)
+		err := renderer.DrawLine(int32(x0), int32(y0), int32(x1), int32(y1))
+		if err != nil {
+			return err
+		}
+		return drawText(renderer, int32(lx)+2, int32(ly)+2, gridScale, label)
+	}
+
+	for n := math.Ceil(reMin / step); n*step <= reMax; n++ {
+		re := n * step
+		labelIm := imMin
+		if settings.Rotation != 0 {
+			labelIm = imMid
+		}
+		err = line(re, imMin, re, imMax, re, labelIm, gridLabel(re, step), n == 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	for n := math.Ceil(imMin / step); n*step <= imMax; n++ {
+		im := n * step
+		labelRe := reMin
+		if settings.Rotation != 0 {
+			labelRe = reMid
+		}
+		err = line(reMin, im, reMax, im, labelRe, im, gridLabel(im, step)+"i", n == 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGridColor draws the axes brighter than the rest of the grid.
+func setGridColor(renderer *sdl.Renderer, axis bool) {
+	if axis {
+		renderer.SetDrawColor(255, 255, 255, 200)
+		return
+	}
+	renderer.SetDrawColor(255, 255, 255, 70)
+}
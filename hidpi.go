@@ -0,0 +1,17 @@
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// displayScale is the ratio of drawable pixels to window coordinates: 2 on
+// a typical Retina display and 1 elsewhere.
+func displayScale(window *sdl.Window, renderer *sdl.Renderer) (float64, error) {
+	w, _ := window.GetSize()
+	outputW, _, err := renderer.GetOutputSize()
+	if err != nil {
+		return 1, err
+	}
+	if w == 0 || outputW == 0 {
+		return 1, nil
+	}
+	return float64(outputW) / float64(w), nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// histogramBin counts the pixels whose iteration count falls in
+// [Start, End).
+type histogramBin struct {
+	Start  int64
+	End    int64
+	Pixels int64
+}
+
+// iterationHistogram bins iterations over [min, max] into bins equal bins;
+// with bins of 0 there is one bin per iteration count. Counts outside the
+// range are left out.
+func iterationHistogram(iterations []int64, bins, min, max int64) []histogramBin {
+	if bins <= 0 || bins > max-min+1 {
+		bins = max - min + 1
+	}
+	width := float64(max-min+1) / float64(bins)
+
+	hist := make([]histogramBin, bins)
+	for i := range hist {
+		hist[i].Start = min + int64(float64(i)*width)
+		hist[i].End = min + int64(float64(i+1)*width)
+	}
+	for _, n := range iterations {
+		if n < min || n > max {
+			continue
+		}
+		bin := int64(float64(n-min) / width)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		hist[bin].Pixels++
+	}
+	return hist
+}
+
+func writeHistogramCSV(path string, hist []histogramBin) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "could not create histogram file")
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"start", "end", "pixels"})
+	for _, b := range hist {
+		w.Write([]string{
+			strconv.FormatInt(b.Start, 10),
+			strconv.FormatInt(b.End, 10),
+			strconv.FormatInt(b.Pixels, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "could not write %s", path)
+	}
+	return errors.Wrapf(f.Close(), "could not write %s", path)
+}
+
+// runHistogram renders settings and writes the histogram of its iteration
+// counts to path, and the render's statistics to stats. A max of 0 means
+// MaxIterations.
+func runHistogram(path string, bins, min, max int64, settings *Settings, stats io.Writer) error {
+	if max <= 0 {
+		max = settings.MaxIterations
+	}
+	if min < 0 || max < min {
+		return errors.Errorf("histogram range [%d, %d] is empty", min, max)
+	}
+
+	r, err := renderImage(settings, false)
+	if err != nil {
+		return err
+	}
+	if err := writeStats(stats, r.Stats); err != nil {
+		return err
+	}
+	return writeHistogramCSV(path, iterationHistogram(r.Iterations, bins, min, max))
+}
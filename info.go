@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// runInfo prints the SDL version, the render drivers and their limits,
+// the driver the app would pick, and the display modes, for -info.
+func runInfo() int {
+	var compiled, linked sdl.Version
+	sdl.VERSION(&compiled)
+	sdl.GetVersion(&linked)
+	fmt.Printf("SDL compiled %d.%d.%d, linked %d.%d.%d (%s)\n",
+		compiled.Major, compiled.Minor, compiled.Patch,
+		linked.Major, linked.Minor, linked.Patch, sdl.GetRevision())
+
+	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
+		log.WithError(err).Error("could not init SDL2")
+		return 1
+	}
+	defer sdl.Quit()
+
+	if driver, err := sdl.GetCurrentVideoDriver(); err == nil {
+		fmt.Printf("video driver: %s\n", driver)
+	}
+
+	fmt.Println("render drivers:")
+	n, err := sdl.GetNumRenderDrivers()
+	if err != nil {
+		log.WithError(err).Error("could not list render drivers")
+		return 1
+	}
+	for i := 0; i < n; i++ {
+		var info sdl.RendererInfo
+		if _, err := sdl.GetRenderDriverInfo(i, &info); err != nil {
+			fmt.Printf("  %d: %v\n", i, err)
+			continue
+		}
+		printRendererInfo(i, info)
+	}
+
+	fmt.Println("chosen driver:")
+	window, err := sdl.CreateWindow("info", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 1, 1, sdl.WINDOW_HIDDEN)
+	if err != nil {
+		fmt.Printf("  could not create a window: %v\n", err)
+	} else {
+		renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+		if err != nil {
+			fmt.Printf("  could not create an accelerated renderer: %v\n", err)
+		} else if info, err := renderer.GetInfo(); err != nil {
+			fmt.Printf("  %v\n", err)
+		} else {
+			printRendererInfo(-1, info)
+		}
+		if renderer != nil {
+			renderer.Destroy()
+		}
+		window.Destroy()
+	}
+
+	fmt.Println("displays:")
+	displays, err := sdl.GetNumVideoDisplays()
+	if err != nil {
+		log.WithError(err).Error("could not list displays")
+		return 1
+	}
+	for d := 0; d < displays; d++ {
+		name, _ := sdl.GetDisplayName(d)
+		fmt.Printf("  %d: %s\n", d, name)
+
+		modes, _ := sdl.GetNumDisplayModes(d)
+		for m := 0; m < modes; m++ {
+			mode, err := sdl.GetDisplayMode(d, m)
+			if err != nil {
+				continue
+			}
+			fmt.Printf("    %dx%d @ %dHz %s\n", mode.W, mode.H, mode.RefreshRate, sdl.GetPixelFormatName(uint(mode.Format)))
+		}
+	}
+	return 0
+}
+
+// printRendererInfo prints one render driver; index -1 leaves the index
+// off.
+func printRendererInfo(index int, info sdl.RendererInfo) {
+	var flags []string
+	for _, f := range []struct {
+		flag uint32
+		name string
+	}{
+		{sdl.RENDERER_SOFTWARE, "software"},
+		{sdl.RENDERER_ACCELERATED, "accelerated"},
+		{sdl.RENDERER_PRESENTVSYNC, "vsync"},
+		{sdl.RENDERER_TARGETTEXTURE, "target-texture"},
+	} {
+		if info.Flags&f.flag != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+
+	prefix := "  "
+	if index >= 0 {
+		prefix = fmt.Sprintf("  %d: ", index)
+	}
+	fmt.Printf("%s%s [%s] max texture %dx%d\n", prefix, info.Name, strings.Join(flags, ", "),
+		info.MaxTextureWidth, info.MaxTextureHeight)
+}
+
+// checkTextureSize reports an error if the renderer can't hold a width by
+// height texture. Renderers that don't report a limit pass.
+func checkTextureSize(renderer *sdl.Renderer, width, height int32) error {
+	info, err := renderer.GetInfo()
+	if err != nil {
+		return errors.Wrap(err, "could not query the renderer")
+	}
+	if (info.MaxTextureWidth > 0 && width > info.MaxTextureWidth) ||
+		(info.MaxTextureHeight > 0 && height > info.MaxTextureHeight) {
+		return errors.Errorf("a %dx%d image is larger than the %s renderer's %dx%d texture limit",
+			width, height, info.Name, info.MaxTextureWidth, info.MaxTextureHeight)
+	}
+	return nil
+}
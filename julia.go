@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FractalMode picks what a pixel's point of the plane stands for: the
+// parameter c of its own orbit, for the Mandelbrot set and its kin, or the
+// starting z of an orbit under the fixed Settings.Julia, for their Julia
+// sets.
+type FractalMode int
+
+const (
+	FractalMandelbrot FractalMode = iota
+	FractalJulia
+)
+
+func (m FractalMode) String() string {
+	if m == FractalJulia {
+		return "julia"
+	}
+	return "mandelbrot"
+}
+
+// juliaStep is how far the arrow keys move the Julia constant unless
+// Settings.JuliaStep says otherwise.
+const juliaStep = 0.01
+
+// juliaView frames the whole of a typical Julia set, which lies within
+// |z| <= 2.
+var juliaView = View{
+	Min:    -2,
+	Max:    2,
+	Center: Point{X: 0, Y: 0},
+}
+
+// defaultJulia is the Julia constant when none is given.
+var defaultJulia = Point{X: -0.8, Y: 0.156}
+
+// seed is the parameter and starting value of the orbit of the point
+// (x, y) of the plane.
+func seed(x, y float64, settings *Settings) (c, z complex128) {
+	p := complex(x, y)
+	if settings.Fractal == FractalJulia {
+		return complex(settings.Julia.X, settings.Julia.Y), p
+	}
+	return p, p
+}
+
+func formatJulia(p Point) string {
+	return fmt.Sprintf("%g%+gi", p.X, p.Y)
+}
+
+// juliaToggle switches between a fractal and its Julia sets, coming back
+// to the view and iteration count that were left, or to the kernel's home
+// view and iterations when starting out in Julia mode.
+type juliaToggle struct {
+	saved           View
+	savedIterations int64
+	entered         bool
+}
+
+func (j *juliaToggle) Toggle(settings *Settings) {
+	if settings.Fractal == FractalJulia {
+		settings.Fractal = FractalMandelbrot
+		if j.entered {
+			settings.ApplyView(j.saved)
+			settings.MaxIterations = j.savedIterations
+		} else if k, err := registrationFor(settings); err == nil {
+			settings.ApplyView(k.Home)
+			settings.MaxIterations = k.Iterations
+			settings.AdjustIterations(0)
+		}
+		log.Info("left Julia mode")
+		return
+	}
+
+	j.saved = View{Min: settings.Min, Max: settings.Max, Center: settings.Center}
+	j.savedIterations = settings.MaxIterations
+	j.entered = true
+	settings.Fractal = FractalJulia
+	settings.ApplyView(juliaView)
+	log.WithField("c", formatJulia(settings.Julia)).Info("entered Julia mode")
+}
+
+// juliaStep is Settings.JuliaStep with 0 meaning the default step.
+func (s *Settings) juliaStep() float64 {
+	if s.JuliaStep <= 0 {
+		return juliaStep
+	}
+	return s.JuliaStep
+}
+
+// MoveJulia nudges the Julia constant by (dx, dy) steps.
+func (s *Settings) MoveJulia(dx, dy float64) {
+	step := s.juliaStep()
+	s.Julia.X += dx * step
+	s.Julia.Y += dy * step
+}
+
+// juliaNotice is the overlay line naming the Julia constant, and which of
+// juliaConstants it is if it is one.
+func juliaNotice(settings *Settings) string {
+	for _, jc := range juliaConstants {
+		if jc.C == settings.Julia {
+			return "julia c = " + formatJulia(settings.Julia) + " (" + jc.Name + ")"
+		}
+	}
+	return "julia c = " + formatJulia(settings.Julia)
+}
+
+// juliaConstant is a Julia constant whose set is well known. All of them
+// lie on or next to the boundary of the Mandelbrot set, where the Julia
+// sets are the most intricate.
+type juliaConstant struct {
+	Name string
+	C    Point
+}
+
+// juliaConstants lists the constants shift+j steps through. Misiurewicz
+// point Mk,p is where the orbit of 0 lands on a cycle of period p after k
+// iterations; its Julia set is a dendrite.
+var juliaConstants = []juliaConstant{
+	{Name: "Dendrite, M2,2", C: Point{X: 0, Y: 1}},
+	{Name: "Misiurewicz M3,1", C: Point{X: -0.22815549365396182, Y: 1.1151425080399373}},
+	{Name: "Misiurewicz M4,1", C: Point{X: -0.10109636384562212, Y: 0.9562865108091415}},
+	{Name: "Real Misiurewicz M3,1", C: Point{X: -1.5436890126920764, Y: 0}},
+	{Name: "Real Misiurewicz M3,2", C: Point{X: -1.8392867552141612, Y: 0}},
+	{Name: "Segment, M2,1", C: Point{X: -2, Y: 0}},
+	{Name: "Cauliflower", C: Point{X: 0.25, Y: 0}},
+	{Name: "San Marco", C: Point{X: -0.75, Y: 0}},
+	{Name: "Parabolic rabbit", C: Point{X: -0.125, Y: 0.649519052838329}},
+	{Name: "Siegel disk", C: Point{X: -0.390540870218399, Y: -0.586787907346969}},
+	{Name: "Default", C: defaultJulia},
+}
+
+// juliaTour steps through juliaConstants in order, wrapping around.
+type juliaTour struct {
+	next int
+}
+
+// Next sets the Julia constant to the next of juliaConstants.
+func (t *juliaTour) Next(settings *Settings) {
+	jc := juliaConstants[t.next]
+	t.next = (t.next + 1) % len(juliaConstants)
+
+	settings.Julia = jc.C
+	log.WithFields(log.Fields{
+		"name": jc.Name,
+		"c":    formatJulia(jc.C),
+	}).Info("switched to a named Julia constant")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+// TestJuliaSetNonEmpty renders the Julia sets of constants from the
+// Mandelbrot set, whose filled Julia sets are connected and have an
+// interior, and checks that they show both interior and escaping pixels.
+func TestJuliaSetNonEmpty(t *testing.T) {
+	constants := []struct {
+		name string
+		c    Point
+	}{
+		{name: "default", c: defaultJulia},
+		{name: "basilica", c: Point{X: -1, Y: 0}},
+		{name: "Douady rabbit", c: Point{X: -0.123, Y: 0.745}},
+	}
+
+	for _, tt := range constants {
+		settings := testSettings(64, 64)
+		settings.Fractal = FractalJulia
+		settings.Julia = tt.c
+		settings.ApplyView(juliaView)
+
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var interior int
+		for _, iters := range r.Iterations {
+			if iters == settings.MaxIterations {
+				interior++
+			}
+		}
+		if interior == 0 || interior == len(r.Iterations) {
+			t.Errorf("%s: %d of %d pixels are in the filled Julia set", tt.name, interior, len(r.Iterations))
+		}
+	}
+}
+
+// TestJuliaToggle checks that leaving Julia mode restores the view and
+// iteration count it was entered from, or the kernel's home view when the
+// session started in Julia mode.
+func TestJuliaToggle(t *testing.T) {
+	settings := testSettings(64, 64)
+	settings.CenterOn(-0.75, 0.1, 0.01)
+	settings.MaxIterations = 900
+	want := settings
+
+	var toggle juliaToggle
+	toggle.Toggle(&settings)
+	if settings.Fractal != FractalJulia {
+		t.Fatal("didn't enter Julia mode")
+	}
+	settings.MaxIterations = 50
+	toggle.Toggle(&settings)
+	if settings.Fractal != FractalMandelbrot || settings.Min != want.Min || settings.Max != want.Max ||
+		settings.Center != want.Center || settings.MaxIterations != want.MaxIterations {
+		t.Errorf("left Julia mode at %+v; want the view it was entered from, %+v", settings, want)
+	}
+
+	started := testSettings(64, 64)
+	started.Fractal = FractalJulia
+	started.ApplyView(juliaView)
+	started.MaxIterations = 50
+	var fresh juliaToggle
+	fresh.Toggle(&started)
+	if started.Min != homeView.Min || started.Max != homeView.Max || started.Center != homeView.Center ||
+		started.MaxIterations != kernels[0].Iterations {
+		t.Errorf("left Julia mode at %+v; want the home view at %d iterations", started, kernels[0].Iterations)
+	}
+}
+
+// TestJuliaProbeAndTour checks that the period probe follows the Julia
+// orbit and the location tour leaves Julia mode.
+func TestJuliaProbeAndTour(t *testing.T) {
+	settings := testSettings(64, 64)
+	settings.Fractal = FractalJulia
+	settings.Julia = Point{X: -1, Y: 0}
+
+	// under z^2 - 1, 0 goes to -1 and back
+	period, escaped, err := orbitPeriod(0, &settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if escaped || period != 2 {
+		t.Errorf("the basilica orbit of 0 has period %d, escaped %v; want period 2", period, escaped)
+	}
+
+	var tour locationTour
+	tour.Next(&settings)
+	if settings.Fractal != FractalMandelbrot {
+		t.Error("the location tour stayed in Julia mode")
+	}
+}
+
+// TestMoveJulia nudges the Julia constant by the default step and by a
+// JuliaStep, and checks the overlay names the constant it lands on.
+func TestMoveJulia(t *testing.T) {
+	settings := testSettings(64, 64)
+	settings.Fractal = FractalJulia
+	settings.Julia = Point{X: -0.8, Y: 0.156}
+	settings.MoveJulia(1, -1)
+	if want := (Point{X: -0.8 + juliaStep, Y: 0.156 - juliaStep}); settings.Julia != want {
+		t.Errorf("the default step moved c to %v; want %v", settings.Julia, want)
+	}
+
+	settings.Julia = Point{X: -1, Y: 0}
+	settings.JuliaStep = 0.25
+	settings.MoveJulia(-1, 2)
+	if want := (Point{X: -1.25, Y: 0.5}); settings.Julia != want {
+		t.Errorf("a step of 0.25 moved c to %v; want %v", settings.Julia, want)
+	}
+	if got, want := juliaNotice(&settings), "julia c = -1.25+0.5i"; got != want {
+		t.Errorf("the overlay reads %q; want %q", got, want)
+	}
+}
+
+// TestJuliaConstants checks that every named Julia constant lies within
+// juliaBoundaryTolerance of the Mandelbrot set's boundary: some point that
+// near it escapes, with the distance estimate putting the set no further
+// away. The tour then steps through them all and the overlay names them.
+func TestJuliaConstants(t *testing.T) {
+	const juliaBoundaryTolerance = 0.01
+	// distance bounds the distance from c to the set by the exterior
+	// distance estimate 2|z|log|z|/|dz/dc|, or reports false if c doesn't
+	// escape
+	distance := func(c complex128) (float64, bool) {
+		var z, dz complex128
+		for i := 0; i < 20000; i++ {
+			dz = 2*z*dz + 1
+			z = z*z + c
+			if m := cmplx.Abs(z); m > 1e10 {
+				return 2 * m * math.Log(m) / cmplx.Abs(dz), true
+			}
+		}
+		return 0, false
+	}
+
+	seen := map[string]bool{}
+	for _, jc := range juliaConstants {
+		if seen[jc.Name] {
+			t.Errorf("%s is listed twice", jc.Name)
+		}
+		seen[jc.Name] = true
+
+		c := complex(jc.C.X, jc.C.Y)
+		best := math.Inf(1)
+		const rings, angles = 8, 64
+		for k := 0; k <= rings; k++ {
+			for a := 0; a < angles; a++ {
+				p := c + cmplx.Rect(juliaBoundaryTolerance/2*float64(k)/rings, 2*math.Pi*float64(a)/angles)
+				if d, ok := distance(p); ok {
+					best = math.Min(best, cmplx.Abs(p-c)+d)
+				}
+			}
+		}
+		if best > juliaBoundaryTolerance {
+			t.Errorf("%s at %s is %v from the boundary; want within %v", jc.Name, formatJulia(jc.C), best, juliaBoundaryTolerance)
+		}
+	}
+
+	settings := testSettings(16, 16)
+	settings.Fractal = FractalJulia
+	var tour juliaTour
+	for _, jc := range juliaConstants {
+		tour.Next(&settings)
+		if settings.Julia != jc.C {
+			t.Errorf("the tour set c = %v; want %s's %v", settings.Julia, jc.Name, jc.C)
+		}
+		if got := juliaNotice(&settings); !strings.HasSuffix(got, "("+jc.Name+")") {
+			t.Errorf("the overlay reads %q; want it to name %s", got, jc.Name)
+		}
+	}
+	tour.Next(&settings)
+	if settings.Julia != juliaConstants[0].C {
+		t.Errorf("the tour went on to %v rather than wrapping around", settings.Julia)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/pkg/errors"
+)
+
+// periodEpsilon is how close an orbit must return to its reference point
+// to be treated as periodic.
+const periodEpsilon = 1e-12
+
+// Kernel runs the escape-time iteration for a single point. c is the
+// parameter and z the starting value; it returns the number of iterations
+// completed before the orbit escaped, the last value of z, and whether it
+// escaped at all.
+type Kernel interface {
+	Iterate(c, z complex128) (iters int, finalZ complex128, escaped bool)
+}
+
+// kernelRegistration describes a fractal: how to build its kernel and the
+// view and iteration count it starts at when selected.
+type kernelRegistration struct {
+	Name       string
+	New        func(settings *Settings) (Kernel, error)
+	Home       View
+	Iterations int64
+}
+
+// kernels lists the available fractals; the first entry is the default.
+var kernels = []kernelRegistration{
+	{Name: "mandelbrot", New: newMandelbrotKernel, Home: homeView, Iterations: 200},
+	{Name: "tricorn", New: newTricornKernel, Home: tricornView, Iterations: 200},
+	{Name: "fixed", New: newFixedKernel, Home: homeView, Iterations: 200},
+	// an arbitrary formula is interpreted, so it starts out cheaper
+	{Name: "formula", New: newFormulaKernel, Home: homeView, Iterations: 100},
+}
+
+// registrationFor finds the registration of the kernel named by
+// settings.Kernel.
+func registrationFor(settings *Settings) (kernelRegistration, error) {
+	for _, k := range kernels {
+		if k.Name == kernelName(settings) {
+			return k, nil
+		}
+	}
+	return kernelRegistration{}, errors.Errorf("unknown fractal %q", settings.Kernel)
+}
+
+// kernelFor builds the kernel named by settings.Kernel; an empty name
+// selects the default.
+func kernelFor(settings *Settings) (Kernel, error) {
+	k, err := registrationFor(settings)
+	if err != nil {
+		return nil, err
+	}
+	return k.New(settings)
+}
+
+// switchKernel selects the named kernel and, unless Settings.KeepView is
+// set, frames its home view at its default iteration count, so switching
+// doesn't land on an empty screen.
+func switchKernel(settings *Settings, name string) error {
+	previous := settings.Kernel
+	settings.Kernel = name
+	k, err := registrationFor(settings)
+	if err != nil {
+		settings.Kernel = previous
+		return err
+	}
+	if settings.KeepView {
+		return nil
+	}
+
+	settings.ApplyView(k.Home)
+	settings.MaxIterations = k.Iterations
+	if settings.IterationLimit > 0 && settings.MaxIterations > settings.IterationLimit {
+		settings.MaxIterations = settings.IterationLimit
+	}
+	return nil
+}
+
+// samplePixel iterates the point of the complex plane under the image
+// position (px, py), which need not be a whole pixel.
+func samplePixel(kernel Kernel, px, py float64, settings *Settings) int64 {
+	x, y := settings.PixelToComplex(px+settings.JitterX, py+settings.JitterY)
+	c, z := seed(x, y, settings)
+	n, _, _ := kernel.Iterate(c, z)
+	return int64(n)
+}
+
+func kernelName(settings *Settings) string {
+	if settings.Kernel == "" {
+		return kernels[0].Name
+	}
+	return settings.Kernel
+}
+
+// cycleKernel steps settings.Kernel forwards or backwards through the
+// registered kernels, skipping the formula kernel when no formula is set.
+func cycleKernel(settings *Settings, dir int) bool {
+	current := 0
+	for i, k := range kernels {
+		if k.Name == kernelName(settings) {
+			current = i
+		}
+	}
+
+	next := current
+	for {
+		next = (next + dir + len(kernels)) % len(kernels)
+		if next == current {
+			return false
+		}
+		if kernels[next].Name != "formula" || settings.Formula != "" {
+			break
+		}
+	}
+
+	if err := switchKernel(settings, kernels[next].Name); err != nil {
+		return false
+	}
+	return true
+}
+
+// bailout is the radius outside of which every orbit diverges.
+const bailout = 2
+
+// smoothBailout is the radius orbits are followed out to for smooth
+// coloring instead; so far out, where an orbit crosses it barely shifts
+// the normalized count, which comes out continuous across the bands.
+const smoothBailout = 256
+
+// bailoutRadius is the radius the kernels escape orbits at for settings.
+func bailoutRadius(settings *Settings) float64 {
+	if settings.SmoothColoring {
+		return smoothBailout
+	}
+	return bailout
+}
+
+// escaped reports whether z has left the circle of the given radius.
+func escaped(z complex128, radius float64) bool {
+	return real(z)*real(z)+imag(z)*imag(z) > radius*radius
+}
+
+// escapedBy is escaped, or with components set the cheaper test of either
+// component passing the bailout radius. That square takes in the
+// modulus circle, so orbits passing through its corners run an iteration
+// or so longer and counts come out slightly higher.
+func escapedBy(z complex128, components bool, radius float64) bool {
+	if components {
+		return math.Abs(real(z)) > radius || math.Abs(imag(z)) > radius
+	}
+	return escaped(z, radius)
+}
+
+// periodChecker detects orbits that have settled into a cycle, using
+// Brent's method: the orbit is compared against a reference point that is
+// refreshed after windows of doubling length, so any cycle no longer than
+// the current window is caught.
+type periodChecker struct {
+	ref    complex128
+	steps  int
+	window int
+}
+
+func newPeriodChecker(z complex128) periodChecker {
+	return periodChecker{ref: z, window: 1}
+}
+
+// Periodic reports whether z has returned to the reference point.
+func (p *periodChecker) Periodic(z complex128) bool {
+	if math.Abs(real(z)-real(p.ref)) < periodEpsilon && math.Abs(imag(z)-imag(p.ref)) < periodEpsilon {
+		return true
+	}
+
+	p.steps++
+	if p.steps == p.window {
+		p.ref = z
+		p.steps = 0
+		p.window *= 2
+	}
+	return false
+}
+
+// mandelbrotKernel iterates z = z^2 + c.
+type mandelbrotKernel struct {
+	maxIterations int64
+	detectPeriod  bool
+	components    bool
+	bailout       float64
+}
+
+func newMandelbrotKernel(settings *Settings) (Kernel, error) {
+	return mandelbrotKernel{
+		maxIterations: settings.MaxIterations,
+		detectPeriod:  settings.PeriodDetection,
+		components:    settings.ComponentBailout,
+		bailout:       bailoutRadius(settings),
+	}, nil
+}
+
+func (k mandelbrotKernel) Iterate(c, z complex128) (int, complex128, bool) {
+	period := newPeriodChecker(z)
+
+	var iters int
+	var i int64
+	for i = 0; i < k.maxIterations; i++ {
+		z = z*z + c
+		if escapedBy(z, k.components, k.bailout) {
+			return iters, z, true
+		}
+		iters++
+
+		// a cycling orbit never escapes, so finish it as interior now
+		if k.detectPeriod && period.Periodic(z) {
+			return int(k.maxIterations), z, false
+		}
+	}
+	return iters, z, false
+}
+
+// tricornKernel iterates z = conj(z)^2 + c, the Mandelbar set.
+type tricornKernel struct {
+	maxIterations int64
+	detectPeriod  bool
+	components    bool
+	bailout       float64
+}
+
+func newTricornKernel(settings *Settings) (Kernel, error) {
+	return tricornKernel{
+		maxIterations: settings.MaxIterations,
+		detectPeriod:  settings.PeriodDetection,
+		components:    settings.ComponentBailout,
+		bailout:       bailoutRadius(settings),
+	}, nil
+}
+
+func (k tricornKernel) Iterate(c, z complex128) (int, complex128, bool) {
+	period := newPeriodChecker(z)
+
+	var iters int
+	var i int64
+	for i = 0; i < k.maxIterations; i++ {
+		z = cmplx.Conj(z)
+		z = z*z + c
+		if escapedBy(z, k.components, k.bailout) {
+			return iters, z, true
+		}
+		iters++
+
+		if k.detectPeriod && period.Periodic(z) {
+			return int(k.maxIterations), z, false
+		}
+	}
+	return iters, z, false
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+const testIterations = 500
+
+// testKernels builds the float64 and fixed-point Mandelbrot kernels, which
+// must agree on every point below.
+func testKernels(t *testing.T) map[string]Kernel {
+	t.Helper()
+	settings := &Settings{MaxIterations: testIterations}
+	kernels := make(map[string]Kernel)
+	for _, name := range []string{"mandelbrot", "fixed"} {
+		settings.Kernel = name
+		k, err := kernelFor(settings)
+		if err != nil {
+			t.Fatalf("kernel %s: %v", name, err)
+		}
+		kernels[name] = k
+	}
+	return kernels
+}
+
+func TestKernelKnownPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       complex128
+		escaped bool
+		iters   int
+	}{
+		{name: "origin", c: 0, iters: testIterations},
+		{name: "period two bulb", c: -1, iters: testIterations},
+		{name: "tip of the antenna", c: -2, iters: testIterations},
+		{name: "Misiurewicz point i", c: 1i, iters: testIterations},
+		{name: "(2, 2) escapes on the first iteration", c: 2 + 2i, escaped: true, iters: 0},
+		{name: "(-2, -2) escapes on the first iteration", c: -2 - 2i, escaped: true, iters: 0},
+		{name: "right of the cardioid", c: 0.5, escaped: true, iters: 3},
+	}
+
+	for name, k := range testKernels(t) {
+		for _, tt := range tests {
+			iters, _, escaped := k.Iterate(tt.c, tt.c)
+			if escaped != tt.escaped || iters != tt.iters {
+				t.Errorf("%s: %s: got %d iterations, escaped %v; want %d, escaped %v",
+					name, tt.name, iters, escaped, tt.iters, tt.escaped)
+			}
+		}
+	}
+}
+
+// TestKernelSymmetry checks that a point and its conjugate escape together,
+// which an escape test that isn't on the modulus gets wrong.
+func TestKernelSymmetry(t *testing.T) {
+	points := []complex128{-0.75 + 0.1i, 0.3 + 1.5i, -1.5 + 0.5i, 0.26 + 0.01i, -0.1 + 1.2i}
+
+	for name, k := range testKernels(t) {
+		for _, c := range points {
+			above, _, _ := k.Iterate(c, c)
+			below, _, _ := k.Iterate(cmplx.Conj(c), cmplx.Conj(c))
+			if above != below {
+				t.Errorf("%s: %v took %d iterations but its conjugate took %d", name, c, above, below)
+			}
+		}
+	}
+}
+
+// TestEveryKernelRuns builds each registered kernel, checking that it keeps
+// the origin and escapes from (2, 2) like the Mandelbrot set does.
+func TestEveryKernelRuns(t *testing.T) {
+	for _, k := range kernels {
+		settings := &Settings{MaxIterations: testIterations, Kernel: k.Name, Formula: "z*z+c"}
+		kernel, err := kernelFor(settings)
+		if err != nil {
+			t.Errorf("kernel %s: %v", k.Name, err)
+			continue
+		}
+		if iters, _, escaped := kernel.Iterate(0, 0); escaped || iters != testIterations {
+			t.Errorf("%s: the origin took %d iterations, escaped %v", k.Name, iters, escaped)
+		}
+		if iters, _, escaped := kernel.Iterate(2+2i, 2+2i); !escaped || iters != 0 {
+			t.Errorf("%s: (2, 2) took %d iterations, escaped %v", k.Name, iters, escaped)
+		}
+	}
+
+	if _, err := kernelFor(&Settings{Kernel: "nonesuch"}); err == nil {
+		t.Error("an unknown kernel was built")
+	}
+}
+
+// TestPeriodDetectionKeepsTheImage renders views with much of the set in
+// them with and without period detection, which only saves work, for each
+// kernel that does it.
+func TestPeriodDetectionKeepsTheImage(t *testing.T) {
+	views := []View{
+		homeView,
+		{Min: -0.02, Max: 0.02, Center: Point{X: 0.745, Y: -0.11}},
+	}
+	for _, name := range []string{"mandelbrot", "tricorn", "fixed", "formula"} {
+		for _, view := range views {
+			settings := testSettings(96, 96)
+			settings.Kernel = name
+			settings.Formula = "z*z+c"
+			settings.MaxIterations = 1000
+			settings.ApplyView(view)
+
+			settings.PeriodDetection = false
+			want, err := renderImage(&settings, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings.PeriodDetection = true
+			got, err := renderImage(&settings, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			interior := 0
+			for i, n := range want.Iterations {
+				if got.Iterations[i] != n {
+					t.Fatalf("%s, %v: pixel %d took %d iterations with period detection and %d without",
+						name, view, i, got.Iterations[i], n)
+				}
+				if n == settings.MaxIterations {
+					interior++
+				}
+			}
+			if interior == 0 {
+				t.Errorf("%s, %v: no pixel is in the set", name, view)
+			}
+			samePixels(t, got.Image.Pix, want.Image.Pix)
+		}
+	}
+}
+
+// TestKernelHomeViews renders each kernel at its home view and iteration
+// count, checking that the view shows structure: some of it in the set,
+// most of it escaping in many different counts, and the set not cut off
+// by the edges of the image.
+func TestKernelHomeViews(t *testing.T) {
+	for _, k := range kernels {
+		settings := testSettings(64, 64)
+		settings.Formula = "z*z+c"
+		if err := switchKernel(&settings, k.Name); err != nil {
+			t.Fatal(err)
+		}
+		if settings.MaxIterations != k.Iterations {
+			t.Errorf("%s: switching gave %d iterations; want %d", k.Name, settings.MaxIterations, k.Iterations)
+		}
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		interior := 0
+		counts := make(map[int64]bool)
+		for i, n := range r.Iterations {
+			if n == settings.MaxIterations {
+				interior++
+				if x, y := i%64, i/64; x == 0 || x == 63 || y == 0 || y == 63 {
+					t.Errorf("%s: (%d, %d) on the edge of the view is in the set", k.Name, x, y)
+				}
+			} else {
+				counts[n] = true
+			}
+		}
+		pixels := len(r.Iterations)
+		// the Tricorn is the thinnest, at about a twentieth of its view
+		if interior < pixels/50 || interior > pixels/2 {
+			t.Errorf("%s: %d of %d pixels are in the set", k.Name, interior, pixels)
+		}
+		if len(counts) < 10 {
+			t.Errorf("%s: the escaping pixels took only %d different counts", k.Name, len(counts))
+		}
+	}
+}
+
+// TestComponentBailoutDifference measures how far the component bailout's
+// counts drift from the modulus test's across each kernel's home view. The
+// square it tests takes in the circle, so no count comes out lower; an
+// orbit that leaves the circle near a corner of the square takes at most
+// a few iterations more to leave the square, and on these views about one
+// pixel in nine does, for a mean of roughly a tenth of an iteration.
+func TestComponentBailoutDifference(t *testing.T) {
+	for _, k := range kernels {
+		settings := testSettings(200, 200)
+		settings.Formula = "z*z+c"
+		if err := switchKernel(&settings, k.Name); err != nil {
+			t.Fatal(err)
+		}
+		settings.MaxIterations = 500
+		modulus, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings.ComponentBailout = true
+		components, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total, largest, differing int64
+		for i, n := range modulus.Iterations {
+			d := components.Iterations[i] - n
+			if d < 0 {
+				t.Fatalf("%s: pixel %d took %d iterations with the component bailout and %d without",
+					k.Name, i, components.Iterations[i], n)
+			}
+			if d > largest {
+				largest = d
+			}
+			if d > 0 {
+				differing++
+			}
+			total += d
+		}
+		mean := float64(total) / float64(len(modulus.Iterations))
+		if largest > 3 || mean <= 0 || mean > 0.25 || differing == 0 {
+			t.Errorf("%s: the component bailout added %.3f iterations a pixel, at most %d, over %d pixels",
+				k.Name, mean, largest, differing)
+		}
+	}
+}
+
+// TestComponentBailout checks that with the component bailout each kernel
+// lets an orbit go as soon as one component passes 2: -2.5i lands on
+// -6.25-2.5i, whose components sum to well below 2.
+func TestComponentBailout(t *testing.T) {
+	for _, k := range kernels {
+		settings := &Settings{MaxIterations: 100, Kernel: k.Name, Formula: "z*z+c", ComponentBailout: true}
+		kernel, err := kernelFor(settings)
+		if err != nil {
+			t.Fatalf("kernel %s: %v", k.Name, err)
+		}
+		if iters, _, escaped := kernel.Iterate(-2.5i, -2.5i); !escaped || iters != 0 {
+			t.Errorf("%s: -2.5i took %d iterations, escaped %v", k.Name, iters, escaped)
+		}
+		if iters, _, escaped := kernel.Iterate(0, 0); escaped || iters != 100 {
+			t.Errorf("%s: the origin took %d iterations, escaped %v", k.Name, iters, escaped)
+		}
+	}
+}
+
+// fixedTolerance is the fraction of pixels whose iteration counts may
+// differ between the fixed-point and float64 kernels. The two round
+// differently, and orbits of points on the boundary are chaotic enough to
+// carry that into a different escape; at the home view about one pixel in
+// eight thousand differs, and at a span of 0.01 about one in six hundred.
+const fixedTolerance = 1.0 / 200
+
+// TestFixedMatchesFloat renders the home view and a moderate zoom with the
+// fixed-point and float64 Mandelbrot kernels, checking that they agree on
+// all but fixedTolerance of the pixels.
+func TestFixedMatchesFloat(t *testing.T) {
+	views := []struct {
+		name  string
+		setup func(s *Settings)
+	}{
+		{name: "home", setup: func(s *Settings) { s.ApplyView(homeView) }},
+		{name: "Seahorse Valley", setup: func(s *Settings) { s.CenterOn(-0.7453, 0.1127, 0.01) }},
+	}
+	for _, v := range views {
+		iterations := make(map[string][]int64)
+		for _, name := range []string{"mandelbrot", "fixed"} {
+			settings := testSettings(128, 128)
+			settings.Kernel = name
+			settings.MaxIterations = 1000
+			v.setup(&settings)
+			r, err := renderImage(&settings, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			iterations[name] = r.Iterations
+		}
+
+		differing := 0
+		for i, want := range iterations["mandelbrot"] {
+			if iterations["fixed"][i] != want {
+				differing++
+			}
+		}
+		if limit := fixedTolerance * float64(len(iterations["fixed"])); float64(differing) > limit {
+			t.Errorf("%s: %d of %d pixels differ; want at most %.0f", v.name, differing, len(iterations["fixed"]), limit)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// keyframe is one stop on an animation path. Ease shapes the segment from
+// this keyframe to the next: linear, in, out or in-out. ColorOffset isn't
+// wrapped between keyframes, so going from 0 to 3 cycles the palette three
+// times.
+type keyframe struct {
+	Time float64 `json:"time"`
+	Ease string  `json:"ease"`
+	viewParams
+	ColorDensity float64  `json:"color_density"`
+	ColorOffset  *float64 `json:"color_offset,omitempty"`
+}
+
+// readKeyframes loads and validates a JSON list of keyframes.
+func readKeyframes(path string) ([]keyframe, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read keyframes")
+	}
+
+	var frames []keyframe
+	if err := json.Unmarshal(data, &frames); err != nil {
+		var offset int64
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			offset = e.Offset
+		case *json.UnmarshalTypeError:
+			offset = e.Offset
+		default:
+			return nil, errors.Wrapf(err, "could not parse %s", path)
+		}
+		line := bytes.Count(data[:offset], []byte("\n")) + 1
+		return nil, errors.Wrapf(err, "could not parse %s at line %d", path, line)
+	}
+
+	if len(frames) < 2 {
+		return nil, errors.Errorf("%s: need at least two keyframes", path)
+	}
+	for i, f := range frames {
+		if i > 0 && f.Time <= frames[i-1].Time {
+			return nil, errors.Errorf("%s: keyframe %d: time %g is not after %g", path, i, f.Time, frames[i-1].Time)
+		}
+		if _, err := easing(f.Ease); err != nil {
+			return nil, errors.Wrapf(err, "%s: keyframe %d", path, i)
+		}
+		if _, err := kernelFor(&Settings{Kernel: f.Kernel, Formula: f.Formula}); err != nil {
+			return nil, errors.Wrapf(err, "%s: keyframe %d", path, i)
+		}
+		if f.MaxIterations < 1 {
+			return nil, errors.Errorf("%s: keyframe %d: iterations must be at least 1", path, i)
+		}
+		if f.Max <= f.Min {
+			return nil, errors.Errorf("%s: keyframe %d: max must be above min", path, i)
+		}
+		if _, err := parseToneCurve(f.ToneCurve); err != nil {
+			return nil, errors.Wrapf(err, "%s: keyframe %d", path, i)
+		}
+		if _, err := parsePalette(f.Palette); err != nil {
+			return nil, errors.Wrapf(err, "%s: keyframe %d", path, i)
+		}
+		if f.ColorDensity < 0 {
+			return nil, errors.Errorf("%s: keyframe %d: color_density can't be negative", path, i)
+		}
+	}
+	return frames, nil
+}
+
+func easeLinear(t float64) float64 { return t }
+func easeIn(t float64) float64     { return t * t }
+func easeOut(t float64) float64    { return 1 - (1-t)*(1-t) }
+func easeInOut(t float64) float64  { return t * t * (3 - 2*t) }
+
+func easing(name string) (func(t float64) float64, error) {
+	switch name {
+	case "", "linear":
+		return easeLinear, nil
+	case "in":
+		return easeIn, nil
+	case "out":
+		return easeOut, nil
+	case "in-out":
+		return easeInOut, nil
+	}
+	return nil, errors.Errorf("unknown ease %q", name)
+}
+
+// logLerp moves geometrically from a to b, so spans shrink or grow at a
+// steady rate.
+func logLerp(a, b, t float64) float64 {
+	return a * math.Pow(b/a, t)
+}
+
+// apply frames settings on the keyframe.
+func (f keyframe) apply(settings *Settings) {
+	settings.Kernel = f.Kernel
+	settings.Formula = f.Formula
+	settings.Center = Point{X: f.CenterX, Y: f.CenterY}
+	settings.Min = f.Min
+	settings.Max = f.Max
+	settings.MaxIterations = f.MaxIterations
+	settings.Rotation = f.Rotation
+	settings.Fractal = FractalMandelbrot
+	if f.Julia {
+		settings.Fractal = FractalJulia
+		settings.Julia = Point{X: f.JuliaX, Y: f.JuliaY}
+	}
+	if f.ToneCurve != "" {
+		settings.ToneCurve, _ = parseToneCurve(f.ToneCurve)
+	}
+	if f.Palette != "" {
+		settings.Palette = f.Palette
+	}
+	if f.ColorDensity > 0 {
+		settings.ColorDensity = f.ColorDensity
+	}
+	if f.ColorOffset != nil {
+		settings.ColorOffset = *f.ColorOffset
+	}
+}
+
+// interpolate sets settings to the point t of the way from a to b, after
+// a's easing. The view centre moves linearly and the span geometrically,
+// so zooms run at a steady rate.
+func interpolate(settings *Settings, a, b keyframe, t float64) {
+	ease, _ := easing(a.Ease)
+	t = ease(t)
+
+	var from, to Settings
+	from.Width, from.Height = settings.Width, settings.Height
+	to.Width, to.Height = settings.Width, settings.Height
+	a.apply(&from)
+	b.apply(&to)
+	fromRe, fromIm := from.ViewCenter()
+	toRe, toIm := to.ViewCenter()
+	fromSpan, toSpan := from.Max-from.Min, to.Max-to.Min
+
+	a.apply(settings)
+	settings.CenterOn(
+		fromRe+(toRe-fromRe)*t,
+		fromIm+(toIm-fromIm)*t,
+		logLerp(fromSpan, toSpan, t))
+	settings.MaxIterations = int64(math.Round(float64(a.MaxIterations) + float64(b.MaxIterations-a.MaxIterations)*t))
+	settings.ColorDensity = from.ColorDensity + (to.ColorDensity-from.ColorDensity)*t
+	settings.ColorOffset = from.ColorOffset + (to.ColorOffset-from.ColorOffset)*t
+	settings.Rotation = a.Rotation + (b.Rotation-a.Rotation)*t
+	// the constant glides between Julia keyframes, sweeping the family
+	if a.Julia && b.Julia {
+		settings.Julia = Point{
+			X: a.JuliaX + (b.JuliaX-a.JuliaX)*t,
+			Y: a.JuliaY + (b.JuliaY-a.JuliaY)*t,
+		}
+	}
+}
+
+// frameJitter is the sub-pixel sample offset for animation frame n, in
+// [-0.5, 0.5) on each axis and the same on every run.
+func frameJitter(n int) (float64, float64) {
+	rng := rand.New(rand.NewSource(int64(n)))
+	return rng.Float64() - 0.5, rng.Float64() - 0.5
+}
+
+// runKeyframes renders the animation in path at fps frames per second
+// into numbered images in dir, writing each frame's statistics to stats.
+func runKeyframes(path, dir string, fps float64, settings *Settings, export exportOptions, stats io.Writer) error {
+	frames, err := readKeyframes(path)
+	if err != nil {
+		return err
+	}
+	if fps <= 0 {
+		return errors.Errorf("frame rate must be positive, got %g", fps)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "could not create the frame directory")
+	}
+	start, end := frames[0].Time, frames[len(frames)-1].Time
+	count := int(math.Floor((end-start)*fps)) + 1
+	segment := 0
+	for n := 0; n < count; n++ {
+		at := start + float64(n)/fps
+		for segment < len(frames)-2 && at >= frames[segment+1].Time {
+			segment++
+		}
+		a, b := frames[segment], frames[segment+1]
+
+		frame := settings.Clone()
+		if a.ColorDensity == 0 {
+			a.ColorDensity = settings.ColorDensity
+		}
+		if b.ColorDensity == 0 {
+			b.ColorDensity = settings.ColorDensity
+		}
+		if a.ColorOffset == nil {
+			a.ColorOffset = &settings.ColorOffset
+		}
+		if b.ColorOffset == nil {
+			b.ColorOffset = &settings.ColorOffset
+		}
+		interpolate(&frame, a, b, math.Min(1, (at-a.Time)/(b.Time-a.Time)))
+		if frame.AnimationJitter {
+			frame.JitterX, frame.JitterY = frameJitter(n)
+		}
+
+		r, err := renderImage(&frame, export.BitDepth == 16)
+		if err != nil {
+			return errors.Wrapf(err, "frame %d", n)
+		}
+		if err := writeStats(stats, r.Stats); err != nil {
+			return err
+		}
+		out := filepath.Join(dir, fmt.Sprintf("frame-%05d%s", n, export.Ext()))
+		if err := writeImage(out, r.Output(export), export); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"frame": n, "of": count}).Debug("rendered frame")
+	}
+	log.WithFields(log.Fields{"frames": count, "dir": dir}).Info("rendered animation")
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// keyBindings names the keys the main loop acts on, so each action can
+// have more than one key.
+type keyBindings struct {
+	Quit []sdl.Keycode
+}
+
+var defaultKeys = keyBindings{
+	Quit: []sdl.Keycode{sdl.K_q, sdl.K_ESCAPE},
+}
+
+// matches reports whether keyCode is one of keys.
+func matches(keyCode sdl.Keycode, keys []sdl.Keycode) bool {
+	for _, k := range keys {
+		if k == keyCode {
+			return true
+		}
+	}
+	return false
+}
+
+// inputPending reports whether the event queue holds input the main loop
+// shouldn't wait for a render to handle: quitting, a key press, a click or
+// the wheel. The events stay queued.
+func inputPending() bool {
+	sdl.PumpEvents()
+	return sdl.HasEvent(sdl.QUIT) || sdl.HasEvent(sdl.KEYDOWN) ||
+		sdl.HasEvent(sdl.MOUSEBUTTONDOWN) || sdl.HasEvent(sdl.MOUSEWHEEL)
+}
@@ -0,0 +1,50 @@
+package main
+
+import log "github.com/sirupsen/logrus"
+
+// famousLocation is a well-known spot on the Mandelbrot set, framed with
+// Span across the image.
+type famousLocation struct {
+	Name       string
+	Re         float64
+	Im         float64
+	Span       float64
+	Iterations int64
+}
+
+var famousLocations = []famousLocation{
+	{Name: "Seahorse Valley", Re: -0.7453, Im: 0.1127, Span: 0.01, Iterations: 500},
+	{Name: "Elephant Valley", Re: 0.282, Im: 0.01, Span: 0.02, Iterations: 500},
+	{Name: "Triple Spiral Valley", Re: -0.088, Im: 0.654, Span: 0.02, Iterations: 600},
+	{Name: "Misiurewicz point c = i", Re: 0, Im: 1, Span: 0.05, Iterations: 500},
+	{Name: "Period-3 minibrot", Re: -1.7687, Im: 0, Span: 0.04, Iterations: 400},
+	{Name: "Deep seahorse", Re: -0.743643887037151, Im: 0.13182590420533, Span: 1e-5, Iterations: 2000},
+}
+
+// locationTour steps through famousLocations in order, wrapping around.
+type locationTour struct {
+	next int
+}
+
+// Next frames settings on the next famous location with the Mandelbrot
+// kernel, leaving Julia mode: the locations are points of the Mandelbrot
+// set itself.
+func (t *locationTour) Next(settings *Settings) {
+	loc := famousLocations[t.next]
+	t.next = (t.next + 1) % len(famousLocations)
+
+	settings.Kernel = "mandelbrot"
+	settings.Fractal = FractalMandelbrot
+	settings.CenterOn(loc.Re, loc.Im, loc.Span)
+	settings.MaxIterations = loc.Iterations
+	if settings.IterationLimit > 0 && settings.MaxIterations > settings.IterationLimit {
+		settings.MaxIterations = settings.IterationLimit
+	}
+
+	log.WithFields(log.Fields{
+		"name": loc.Name,
+		"re":   loc.Re,
+		"im":   loc.Im,
+		"span": loc.Span,
+	}).Info("jumped to a famous location")
+}
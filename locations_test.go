@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestFamousLocations frames each famous location, checking that it lies
+// within the set's bounds and that the view it gives straddles the
+// boundary, with points both in the set and escaping.
+func TestFamousLocations(t *testing.T) {
+	settings := testSettings(48, 48)
+	var tour locationTour
+	for _, loc := range famousLocations {
+		if loc.Re < -2 || loc.Re > 0.5 || loc.Im < -1.2 || loc.Im > 1.2 {
+			t.Errorf("%s at %v%+vi is outside the set's bounds", loc.Name, loc.Re, loc.Im)
+		}
+
+		tour.Next(&settings)
+		re, im := settings.ViewCenter()
+		span := settings.Max - settings.Min
+		if math.Abs(re-loc.Re) > span*1e-6 || math.Abs(im-loc.Im) > span*1e-6 || math.Abs(span-loc.Span) > span*1e-6 {
+			t.Errorf("%s: framed %v%+vi with a span of %v; want %v%+vi and %v",
+				loc.Name, re, im, span, loc.Re, loc.Im, loc.Span)
+		}
+		if settings.MaxIterations != loc.Iterations {
+			t.Errorf("%s: %d iterations; want %d", loc.Name, settings.MaxIterations, loc.Iterations)
+		}
+
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		interior, escaping := 0, 0
+		for _, n := range r.Iterations {
+			if n == settings.MaxIterations {
+				interior++
+			} else {
+				escaping++
+			}
+		}
+		// the Misiurewicz point is a dendrite, too thin to land a pixel on
+		if escaping == 0 || interior == 0 && loc.Im != 1 {
+			t.Errorf("%s: %d pixels in the set and %d escaping; want both", loc.Name, interior, escaping)
+		}
+	}
+
+	tour.Next(&settings)
+	if re, _ := settings.ViewCenter(); math.Abs(re-famousLocations[0].Re) > 1e-9 {
+		t.Errorf("the tour went on to %v rather than wrapping around", re)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// macroStep is one recorded key press, At after the recording started,
+// with the modifiers held for it.
+type macroStep struct {
+	At  time.Duration
+	Key sdl.Keycode
+	Mod uint16
+}
+
+// macroModifiers are the modifiers a macro keeps, by the name it writes
+// them under. Either side of the keyboard records as the left key, which
+// is what a replay presses.
+var macroModifiers = []struct {
+	name  string
+	mask  uint16
+	press uint16
+}{
+	{"Ctrl", sdl.KMOD_CTRL, sdl.KMOD_LCTRL},
+	{"Shift", sdl.KMOD_SHIFT, sdl.KMOD_LSHIFT},
+	{"Alt", sdl.KMOD_ALT, sdl.KMOD_LALT},
+	{"GUI", sdl.KMOD_GUI, sdl.KMOD_LGUI},
+}
+
+// macroMod reduces Keysym.Mod to the modifiers a macro keeps, dropping
+// the lock keys.
+func macroMod(mod uint16) uint16 {
+	var kept uint16
+	for _, m := range macroModifiers {
+		if mod&m.mask != 0 {
+			kept |= m.press
+		}
+	}
+	return kept
+}
+
+// macroKeyName names a key press as, e.g., "Ctrl+Shift+S".
+func macroKeyName(s macroStep) string {
+	name := sdl.GetKeyName(s.Key)
+	for k := len(macroModifiers) - 1; k >= 0; k-- {
+		if s.Mod&macroModifiers[k].mask != 0 {
+			name = macroModifiers[k].name + "+" + name
+		}
+	}
+	return name
+}
+
+// parseMacroKey is the inverse of macroKeyName, taking the modifiers in
+// any order. They come off the front, so a key that is itself named "+"
+// still parses.
+func parseMacroKey(text string) (sdl.Keycode, uint16) {
+	var mod uint16
+	for stripped := true; stripped; {
+		stripped = false
+		for _, m := range macroModifiers {
+			if rest := strings.TrimPrefix(text, m.name+"+"); rest != text && rest != "" {
+				text, mod, stripped = rest, mod|m.press, true
+			}
+		}
+	}
+	return sdl.GetKeyFromName(text), mod
+}
+
+// writeMacro saves steps one per line as "<milliseconds> <key name>", the
+// key name led by any modifiers as in "Ctrl+S", so macros can be written
+// or edited by hand.
+func writeMacro(path string, steps []macroStep) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "could not create macro file")
+	}
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# milliseconds key")
+	for _, s := range steps {
+		fmt.Fprintf(w, "%d %s\n", s.At.Milliseconds(), macroKeyName(s))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "could not write %s", path)
+	}
+	return errors.Wrapf(f.Close(), "could not write %s", path)
+}
+
+func readMacro(path string) ([]macroStep, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open macro file")
+	}
+	defer f.Close()
+
+	var steps []macroStep
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("%s:%d: want \"<milliseconds> <key>\"", path, line)
+		}
+		ms, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s:%d: bad time", path, line)
+		}
+		key, mod := parseMacroKey(strings.TrimSpace(fields[1]))
+		if key == sdl.K_UNKNOWN {
+			return nil, errors.Errorf("%s:%d: unknown key %q", path, line, fields[1])
+		}
+		steps = append(steps, macroStep{At: time.Duration(ms) * time.Millisecond, Key: key, Mod: mod})
+	}
+	return steps, errors.Wrapf(scanner.Err(), "could not read %s", path)
+}
+
+// macroRecorder records key presses while Recording and saves them to
+// path when recording stops.
+type macroRecorder struct {
+	Recording bool
+	path      string
+	start     time.Time
+	steps     []macroStep
+}
+
+func (m *macroRecorder) Toggle() {
+	if !m.Recording {
+		m.Recording = true
+		m.start = time.Now()
+		m.steps = m.steps[:0]
+		log.WithField("path", m.path).Info("recording a macro")
+		return
+	}
+
+	m.Recording = false
+	if err := writeMacro(m.path, m.steps); err != nil {
+		log.WithError(err).Error("could not save the macro")
+		return
+	}
+	log.WithFields(log.Fields{"path": m.path, "keys": len(m.steps)}).Info("saved the macro")
+}
+
+func (m *macroRecorder) Record(key sdl.Keysym) {
+	if m.Recording {
+		m.steps = append(m.steps, macroStep{At: time.Since(m.start), Key: key.Sym, Mod: macroMod(key.Mod)})
+	}
+}
+
+// macroPlayer replays a macro by pushing its key presses onto the SDL
+// event queue as they come due.
+type macroPlayer struct {
+	steps []macroStep
+	start time.Time
+	next  int
+}
+
+func (p *macroPlayer) Start(steps []macroStep) {
+	p.steps = steps
+	p.start = time.Now()
+	p.next = 0
+}
+
+// Playing reports whether key presses are still to come.
+func (p *macroPlayer) Playing() bool {
+	return p.next < len(p.steps)
+}
+
+func (p *macroPlayer) Inject() error {
+	for p.next < len(p.steps) && time.Since(p.start) >= p.steps[p.next].At {
+		_, err := sdl.PushEvent(&sdl.KeyboardEvent{
+			Type:      sdl.KEYDOWN,
+			Timestamp: sdl.GetTicks(),
+			State:     sdl.PRESSED,
+			Keysym:    sdl.Keysym{Sym: p.steps[p.next].Key, Mod: p.steps[p.next].Mod},
+		})
+		if err != nil {
+			return err
+		}
+		p.next++
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// TestMacroModifiers records key presses with modifiers held, saves them
+// and reads them back, checking that the modifiers survive along with the
+// keys, that the lock keys and the side of the keyboard are dropped, and
+// that a macro written by hand in the old keys-only form still loads.
+func TestMacroModifiers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "macro.txt")
+	recorder := macroRecorder{path: path}
+	recorder.Toggle()
+	recorder.Record(sdl.Keysym{Sym: sdl.K_s, Mod: sdl.KMOD_RCTRL | sdl.KMOD_NUM})
+	recorder.Record(sdl.Keysym{Sym: sdl.K_y, Mod: sdl.KMOD_LSHIFT})
+	recorder.Record(sdl.Keysym{Sym: sdl.K_PLUS, Mod: sdl.KMOD_LCTRL | sdl.KMOD_RALT})
+	recorder.Record(sdl.Keysym{Sym: sdl.K_y})
+	recorder.Toggle()
+
+	steps, err := readMacro(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []macroStep
+	for _, s := range steps {
+		got = append(got, macroStep{Key: s.Key, Mod: s.Mod})
+	}
+	want := []macroStep{
+		{Key: sdl.K_s, Mod: sdl.KMOD_LCTRL},
+		{Key: sdl.K_y, Mod: sdl.KMOD_LSHIFT},
+		{Key: sdl.K_PLUS, Mod: sdl.KMOD_LCTRL | sdl.KMOD_LALT},
+		{Key: sdl.K_y},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %v; want %v", got, want)
+	}
+
+	hand := filepath.Join(t.TempDir(), "hand.txt")
+	err = os.WriteFile(hand, []byte("# milliseconds key\n0 Y\n250 Shift+Ctrl+Z\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err = readMacro(hand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []macroStep{
+		{Key: sdl.K_y},
+		{At: 250 * time.Millisecond, Key: sdl.K_z, Mod: sdl.KMOD_LCTRL | sdl.KMOD_LSHIFT},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("read %v from the hand-written macro; want %v", steps, want)
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
 	"math"
+	"math/rand"
 	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -15,175 +25,1156 @@ type Point struct {
 	Red   uint8
 	Green uint8
 	Blue  uint8
+
+	Iterations int64
+	// Smooth is the normalized iteration count; see smoothIterations.
+	Smooth float64
+	// Transparent points get alpha 0; see AlphaMode.
+	Transparent bool
+	// Generation is the render a point sent on Jobs belongs to;
+	// imageWriter drops the points of renders since superseded.
+	Generation int64
 }
 
 type Settings struct {
+	// Width and Height are the resolution the image is computed at, which
+	// SDL scales to whatever size the window is.
 	Width         float64
 	Height        float64
 	Min           float64
 	Max           float64
 	MaxIterations int64
 	Center        Point
+	Kernel        string
+	Formula       string
+
+	// KeepView keeps the current view and iteration count when switching
+	// fractals instead of moving to the new fractal's home view.
+	KeepView bool
+
+	// MinColorThreshold clamps escape colors (on the 0-255 scale) below it
+	// to black, hiding the slow-escaping points near the boundary. 0 keeps
+	// the full gradient.
+	MinColorThreshold float64
+
+	// VarianceWindow is the side, in pixels, of the square regions compared
+	// when auto-exploring towards the most detailed part of the view.
+	VarianceWindow int64
+
+	// Dither adds an ordered dither before channels are quantized to 8 bits
+	// to break up banding in smooth gradients.
+	Dither bool
+
+	// LinearLight treats computed colors as linear intensities and encodes
+	// them to sRGB on output.
+	LinearLight bool
+
+	// PeriodDetection stops iterating points whose orbit has become
+	// periodic and classifies them as interior straight away.
+	PeriodDetection bool
+
+	// ComponentBailout escapes orbits once either component passes the
+	// bailout radius rather than the modulus, which is cheaper but counts
+	// slightly more iterations.
+	ComponentBailout bool
+
+	// AdaptiveAA supersamples, with AASamples x AASamples samples, only the
+	// pixels whose iteration count differs from a neighbour's by more than
+	// EdgeThreshold.
+	AdaptiveAA    bool
+	AASamples     int64
+	EdgeThreshold float64
+
+	// DetailRegion is the side in pixels of the square the detail boost
+	// supersamples around a clicked point, with DetailSamples x
+	// DetailSamples samples per pixel.
+	DetailRegion  int64
+	DetailSamples int64
+
+	// BlurRadius softens the displayed image with a box blur of this
+	// radius in pixels; 0 turns it off.
+	BlurRadius int64
+
+	// BackgroundColor fills the image before any pixel is rendered.
+	BackgroundColor color.RGBA
+
+	// ToneMap compresses the iteration range before coloring.
+	ToneMap ToneMap
+
+	// Interpolation overrides how gradient palettes blend between their
+	// stops; the default keeps each gradient's own.
+	Interpolation Interpolation
+
+	// ToneCurve reshapes each channel of the palette for finer control
+	// over contrast.
+	ToneCurve toneCurve
+
+	// Palette names the palette escaping pixels are colored with; empty
+	// selects the default.
+	Palette string
+
+	// Fractal is whether pixels are the parameter of their orbit or, for
+	// Julia sets, its start, with Julia as the parameter.
+	Fractal FractalMode
+	Julia   Point
+
+	// JuliaStep is how far the arrow keys move Julia; 0 is juliaStep.
+	JuliaStep float64
+
+	// SmoothColoring colors escaping pixels by their normalized iteration
+	// count rather than the whole count, so the colors don't band. Orbits
+	// are followed out to smoothBailout for it.
+	SmoothColoring bool
+
+	// SmoothBlend is how far SmoothColoring goes from the whole count to
+	// the normalized one, from 0, banded, to 1, fully smooth.
+	SmoothBlend float64
+
+	// ColorOverrides colors every pixel that took exactly a listed number
+	// of iterations, ahead of the palette, to pick out single bands.
+	ColorOverrides map[int]color.RGBA
+
+	// AlphaMode makes interior or exterior pixels transparent, so exports
+	// can be layered over other images.
+	AlphaMode AlphaMode
+	// Invert runs the palette backwards over the iteration range.
+	Invert bool
+	// ColorDensity is how many times the palette repeats over the
+	// iteration range.
+	ColorDensity float64
+	// ColorOffset shifts where on the palette the iteration range starts,
+	// wrapping around; animating it cycles the colors.
+	ColorOffset float64
+
+	// RenderTimeout bounds how long a render may take; pixels not started
+	// by then are left as they were. 0 disables it.
+	RenderTimeout time.Duration
+
+	// Workers is how many goroutines the headless renders compute on; 0
+	// means one per CPU. The window's come from MandelbrotImage.Workers.
+	Workers int
+
+	// Relief lights the image as a height field of iteration counts, from
+	// LightAzimuth degrees around and LightElevation degrees up.
+	Relief         bool
+	LightAzimuth   float64
+	LightElevation float64
+
+	// Contours overlays iso-iteration lines every ContourSpacing levels.
+	Contours       bool
+	ContourSpacing int64
+
+	// IterationBudget is the total iteration count above which a render
+	// is reported as expensive; 0 disables the warning.
+	IterationBudget int64
+
+	// InitialSpan is the span of the startup view, which magnification is
+	// measured against.
+	InitialSpan float64
+
+	// MaxSpan is the widest view zooming out can reach; 0 leaves it
+	// unbounded.
+	MaxSpan float64
+
+	// AnimationJitter gives each animation frame its own sub-pixel sample
+	// offset, seeded from the frame number, so aliasing changes from frame
+	// to frame instead of crawling. JitterX and JitterY are the offset.
+	AnimationJitter bool
+	JitterX         float64
+	JitterY         float64
+
+	// PixelAspect is the width of a displayed pixel over its height, for
+	// targets with non-square pixels; 0 or 1 mean square.
+	PixelAspect float64
+
+	// Rotation turns the view by this many radians about its middle.
+	Rotation float64
+
+	// ROI limits rendering to this region of the image, leaving the rest
+	// the background color; an empty region renders everything.
+	ROI image.Rectangle
+
+	// PixelBlock computes one pixel per PixelBlock x PixelBlock block and
+	// copies it over the block, for fast low-resolution renders; 0 or 1
+	// compute every pixel.
+	PixelBlock int64
+
+	// MaxPasses is how many jittered passes a full render is averaged
+	// over, refining the image progressively; 1 renders a single pass.
+	MaxPasses int64
+
+	// ZoomFactor is how much each press of + or - scales the view.
+	ZoomFactor float64
+
+	// IterationStep is how far the [ and ] keys move MaxIterations, which
+	// is kept between 1 and IterationLimit.
+	IterationStep  int64
+	IterationLimit int64
+
+	// IterationsPerZoomStep is added to MaxIterations on each zoom in with
+	// + and taken off on each zoom out with -; 0 leaves MaxIterations to
+	// [ and ] alone.
+	IterationsPerZoomStep int64
+}
+
+// Clone returns an independent copy of s for snapshots such as animation
+// frames and previews. Slice, map and pointer fields must be deep-copied
+// here.
+func (s Settings) Clone() Settings {
+	if s.ColorOverrides != nil {
+		overrides := make(map[int]color.RGBA, len(s.ColorOverrides))
+		for iters, c := range s.ColorOverrides {
+			overrides[iters] = c
+		}
+		s.ColorOverrides = overrides
+	}
+	return s
+}
+
+// AdjustColorDensity steps ColorDensity up (dir > 0) or down by a quarter,
+// keeping it at least a quarter, and reports whether it changed.
+func (s *Settings) AdjustColorDensity(dir int) bool {
+	n := s.ColorDensity + 0.25*float64(dir)
+	if n < 0.25 {
+		return false
+	}
+	s.ColorDensity = n
+	return true
+}
+
+// AdjustSmoothBlend steps SmoothBlend up (dir > 0) or down by one of
+// smoothBlendSteps within [0, 1], and reports whether it changed.
+func (s *Settings) AdjustSmoothBlend(dir int) bool {
+	n := (math.Round(s.SmoothBlend*smoothBlendSteps) + float64(dir)) / smoothBlendSteps
+	n = math.Max(0, math.Min(1, n))
+	if n == s.SmoothBlend {
+		return false
+	}
+	s.SmoothBlend = n
+	return true
+}
+
+// ShiftColorOffset moves ColorOffset by a twentieth of the palette in the
+// direction of dir, kept in [0, 1).
+func (s *Settings) ShiftColorOffset(dir int) {
+	s.ColorOffset = math.Mod(s.ColorOffset+colorOffsetStep*float64(dir)+1, 1)
+}
+
+// AdjustIterations moves MaxIterations by delta, clamped to
+// [1, IterationLimit], and reports whether it changed.
+func (s *Settings) AdjustIterations(delta int64) bool {
+	n := s.MaxIterations + delta
+	if n < 1 {
+		n = 1
+	}
+	if s.IterationLimit > 0 && n > s.IterationLimit {
+		n = s.IterationLimit
+	}
+
+	changed := n != s.MaxIterations
+	s.MaxIterations = n
+	return changed
 }
 
 type MandelbrotImage struct {
-	mu       sync.Mutex
-	Width    float64
-	Height   float64
-	Pixels   []byte
-	Settings *Settings
-	Jobs     chan Point
+	mu         sync.Mutex
+	generation int64
+	Width      float64
+	Height     float64
+	Pixels     []byte
+	Iterations []int64
+	Smooth     []float64
+	Settings   *Settings
+	Jobs       chan Point
+
+	// Workers is how many goroutines ForceRender computes pixels on; 0
+	// means one per CPU.
+	Workers int
+
+	// work carries the pixels of each frame to the render pool, which
+	// NewMandelbrotImage starts and later renders resize to Workers;
+	// poolSize is how many goroutines it has.
+	work     chan renderJob
+	poolSize int
+
+	// Interrupt, if set, is polled on the rendering goroutine every
+	// InterruptEvery columns while ForceRender blocks; reporting true cuts
+	// the render short there, so input doesn't wait for the whole frame.
+	// interrupted is whether it cut the latest render short.
+	Interrupt      func() bool
+	InterruptEvery int64
+	interrupted    bool
+
+	// senders tracks the background work that may still send on Jobs,
+	// and written is closed once imageWriter has drawn the last of it.
+	// closing is 1 once Close has been called, which stops that work as a
+	// newer render would.
+	senders sync.WaitGroup
+	written chan struct{}
+	closing int32
+
+	blurred     []byte
+	blurScratch []byte
+	contoured   []byte
+	shaded      []byte
+
+	colors *colorTable
+
+	// passing is 1 while accumulatePasses is refining the frame.
+	passing int32
+
+	// render is the context of the latest ForceRender; it is done once
+	// that render, edge refinement included, has finished.
+	render       context.Context
+	cancelRender context.CancelFunc
 }
 
 func NewMandelbrotImage(width, height float64, settings *Settings) *MandelbrotImage {
-	return &MandelbrotImage{
-		Width:    width,
-		Height:   height,
-		Pixels:   make([]byte, int(width*height*4)),
-		Settings: settings,
-		Jobs:     make(chan Point),
+	mi := &MandelbrotImage{
+		Width:      width,
+		Height:     height,
+		Pixels:     make([]byte, int(width*height*4)),
+		Iterations: make([]int64, int(width*height)),
+		Smooth:     make([]float64, int(width*height)),
+		Settings:   settings,
+		Jobs:       make(chan Point),
+		written:    make(chan struct{}),
+		work:       make(chan renderJob),
 	}
+	mi.sizePool(mi.workers())
+	return mi
 }
 
+// Resize changes the image dimensions, reusing the existing pixel and
+// iteration buffers whenever their capacity is large enough. The buffer
+// contents are left as they were; call Init to clear them.
+func (mi *MandelbrotImage) Resize(width, height float64) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	n := int(width * height)
+	if cap(mi.Pixels) >= n*4 {
+		mi.Pixels = mi.Pixels[:n*4]
+	} else {
+		mi.Pixels = make([]byte, n*4)
+	}
+	if cap(mi.Iterations) >= n {
+		mi.Iterations = mi.Iterations[:n]
+	} else {
+		mi.Iterations = make([]int64, n)
+	}
+	if cap(mi.Smooth) >= n {
+		mi.Smooth = mi.Smooth[:n]
+	} else {
+		mi.Smooth = make([]float64, n)
+	}
+
+	mi.Width = width
+	mi.Height = height
+}
+
+// Init fills the whole buffer with Settings.BackgroundColor, the color
+// pixels show until they are rendered.
 func (mi *MandelbrotImage) Init() {
-	var i uint64
-	for i = 0; i < uint64(mi.Width*mi.Height); i += 4 {
-		mi.Pixels[i] = 0
-		mi.Pixels[i+1] = 0
-		mi.Pixels[i+2] = 0
-		mi.Pixels[i+3] = 0
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	bg := mi.Settings.BackgroundColor
+	for i := 0; i < len(mi.Pixels); i += 4 {
+		mi.Pixels[i] = bg.B
+		mi.Pixels[i+1] = bg.G
+		mi.Pixels[i+2] = bg.R
+		mi.Pixels[i+3] = 255
 	}
 }
 
 func (mi *MandelbrotImage) DrawPoint(point Point) {
 	mi.mu.Lock()
 	defer mi.mu.Unlock()
+	mi.drawPoint(point)
+}
+
+// drawPoint is DrawPoint for callers already holding mi.mu.
+func (mi *MandelbrotImage) drawPoint(point Point) {
 	idx := (int(point.Y) * int(mi.Width) * 4) + (int(point.X) * 4)
 
 	mi.Pixels[idx] = point.Red
 	mi.Pixels[idx+1] = point.Green
 	mi.Pixels[idx+2] = point.Blue
 	mi.Pixels[idx+3] = 255
+	if point.Transparent {
+		mi.Pixels[idx+3] = 0
+	}
+
+	mi.Iterations[int(point.Y)*int(mi.Width)+int(point.X)] = point.Iterations
+	mi.Smooth[int(point.Y)*int(mi.Width)+int(point.X)] = point.Smooth
 }
 
+// Recolor recomputes every pixel's color from the cached iteration counts,
+// for changes that only affect coloring. Edge anti-aliasing needs fresh
+// samples, so it is re-run in the background afterwards.
+func (mi *MandelbrotImage) Recolor() {
+	frame := mi.Settings.Clone()
+	settings := &frame
+	colors := mi.colorTable(settings)
+	generation := atomic.AddInt64(&mi.generation, 1)
+
+	mi.mu.Lock()
+	width := int(mi.Width)
+	for idx, iters := range mi.Iterations {
+		if !settings.inBounds(idx%width, idx/width) {
+			continue
+		}
+		red, green, blue := pixelColor(colors, iters, mi.Smooth[idx], settings)
+		pt := pixelPoint(float64(idx%width), float64(idx/width), red, green, blue, iters, settings)
+		pt.Smooth = mi.Smooth[idx]
+		mi.drawPoint(pt)
+	}
+	mi.mu.Unlock()
+
+	if settings.AdaptiveAA {
+		kernel, err := kernelFor(settings)
+		if err != nil {
+			log.WithError(err).Error("could not set up the fractal kernel")
+			return
+		}
+		mi.senders.Add(1)
+		go func() {
+			defer mi.senders.Done()
+			mi.refineEdges(kernel, settings, generation)
+		}()
+	}
+}
+
+// ForceRender renders the frame, returning once every pixel is drawn or
+// Interrupt cuts it short; the caller is blocked meanwhile, which is what
+// Interrupt is for. Edge refinement and extra passes carry on in the
+// background.
 func (mi *MandelbrotImage) ForceRender() {
-	var wg sync.WaitGroup
+	frame := mi.Settings.Clone()
+	mi.renderWith(&frame)
+}
+
+// workerCount is how many goroutines a worker setting of n asks for, one
+// per CPU when n is 0.
+func workerCount(n int) int {
+	if n < 1 {
+		return runtime.NumCPU()
+	}
+	return n
+}
+
+func (mi *MandelbrotImage) workers() int {
+	return workerCount(mi.Workers)
+}
+
+// ForcePreview renders at factor times MaxIterations, and with fast set
+// without edge anti-aliasing, for quick feedback while the view is moving.
+// A block above 1 computes one pixel per block x block square.
+func (mi *MandelbrotImage) ForcePreview(factor float64, fast bool, block int64) {
+	preview := mi.Settings.Clone()
+	preview.MaxIterations = int64(float64(preview.MaxIterations) * factor)
+	if preview.MaxIterations < 1 {
+		preview.MaxIterations = 1
+	}
+	preview.MaxPasses = 1
+	preview.PixelBlock = block
+	if fast || block > 1 {
+		preview.AdaptiveAA = false
+	}
+	mi.renderWith(&preview)
+}
+
+// renderWith renders the frame for settings, a Clone the caller doesn't
+// change again: the refinement carrying on in the background reads it too.
+func (mi *MandelbrotImage) renderWith(settings *Settings) {
+	kernel, err := kernelFor(settings)
+	if err != nil {
+		log.WithError(err).Error("could not set up the fractal kernel")
+		return
+	}
+
+	colors := mi.colorTable(settings)
+	generation := atomic.AddInt64(&mi.generation, 1)
+	mi.interrupted = false
+	if mi.uniformFill(settings, kernel, colors) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if settings.RenderTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), settings.RenderTimeout)
+	}
+	mi.render, mi.cancelRender = ctx, cancel
+
+	// the lower half of a symmetric view is mirrored from the upper half,
+	// as long as rows aren't computed a block at a time
+	block := settings.pixelBlock()
+	mirror := symmetricView(settings) && block == 1
+
+	bounds := settings.renderBounds()
+
+	mi.sizePool(mi.workers())
+	frame := &renderFrame{
+		ctx:      ctx,
+		settings: settings,
+		kernel:   kernel,
+		colors:   colors,
+		mirror:   mirror,
+	}
+
 	var i int64
 	var j int64
-	for i = 0; i < int64(mi.Width); i++ {
-		for j = 0; j < int64(mi.Height); j++ {
-			pt := Point{
-				X: float64(i),
-				Y: float64(j),
+	var columns int64
+	for i = int64(bounds.Min.X); i < int64(bounds.Max.X); i += block {
+		if columns++; mi.Interrupt != nil && columns%mi.interruptEvery() == 0 && mi.Interrupt() {
+			mi.interrupted = true
+			cancel()
+			break
+		}
+		for j = int64(bounds.Min.Y); j < int64(bounds.Max.Y); j += block {
+			if mirror && mirroredRow(float64(j), settings) {
+				continue
+			}
+			frame.pending.Add(1)
+			mi.work <- renderJob{
+				X:          float64(i),
+				Y:          float64(j),
+				Generation: generation,
+				frame:      frame,
 			}
-			wg.Add(1)
-			go mandelbrotWorker(&wg, pt, mi.Jobs, mi.Settings)
 		}
 	}
+	frame.pending.Wait()
 
+	interrupted := mi.interrupted
+	mi.senders.Add(1)
 	go func() {
-		wg.Wait()
+		defer mi.senders.Done()
+		defer cancel()
+		if ctx.Err() == context.DeadlineExceeded {
+			log.WithField("timeout", settings.RenderTimeout).Warn("render truncated")
+			return
+		}
+		if interrupted {
+			return
+		}
+		reportIterations(atomic.LoadInt64(&frame.total), settings)
+		if settings.MaxPasses > 1 {
+			// the passes supersample every pixel, so edge refinement
+			// would be wasted; they don't count as rendering either, so
+			// recoloring can interrupt them
+			cancel()
+			mi.accumulatePasses(kernel, settings, generation)
+		} else if settings.AdaptiveAA {
+			mi.refineEdges(kernel, settings, generation)
+		}
 	}()
+}
+
+func (mi *MandelbrotImage) interruptEvery() int64 {
+	if mi.InterruptEvery < 1 {
+		return 1
+	}
+	return mi.InterruptEvery
+}
 
+// Interrupted reports whether Interrupt cut the latest render short, in
+// which case the frame still needs rendering.
+func (mi *MandelbrotImage) Interrupted() bool {
+	return mi.interrupted
 }
 
+// Rendering reports whether the latest ForceRender is still running.
+func (mi *MandelbrotImage) Rendering() bool {
+	return mi.render != nil && mi.render.Err() == nil
+}
+
+// Cancel stops the render in progress, if there is one, and reports
+// whether there was.
+func (mi *MandelbrotImage) Cancel() bool {
+	if !mi.Rendering() {
+		return false
+	}
+	atomic.AddInt64(&mi.generation, 1)
+	mi.cancelRender()
+	return true
+}
+
+// stale reports whether the work of the render generation should stop:
+// a newer render has started since, or the image is closing.
+func (mi *MandelbrotImage) stale(generation int64) bool {
+	return atomic.LoadInt32(&mi.closing) == 1 || mi.superseded(generation)
+}
+
+// superseded reports whether a newer render has started since generation.
+func (mi *MandelbrotImage) superseded(generation int64) bool {
+	return atomic.LoadInt64(&mi.generation) != generation
+}
+
+// Close stops the background work of the latest render, waits for
+// imageWriter to draw what it had already sent, and then stops the writer.
+// The image must have a writer running.
 func (mi *MandelbrotImage) Close() {
+	atomic.StoreInt32(&mi.closing, 1)
+	if mi.cancelRender != nil {
+		mi.cancelRender()
+	}
+	mi.senders.Wait()
+	mi.sizePool(0)
 	close(mi.Jobs)
+	<-mi.written
 }
 
 func mapToRange(val, in_min, in_max, out_min, out_max float64) float64 {
 	return (val-in_min)*(out_max-out_min)/(in_max-in_min) + out_min
 }
 
+// imageWriter draws the points sent on jobs until Close closes it, except
+// those of a render that has been superseded since they were computed.
 func imageWriter(mi *MandelbrotImage, jobs chan Point) {
-	for {
-		select {
-		case pt := <-jobs:
-			{
-				mi.DrawPoint(pt)
-			}
-
-		default:
-			{
-			}
+	defer close(mi.written)
+	for pt := range jobs {
+		mi.mu.Lock()
+		if !mi.superseded(pt.Generation) {
+			mi.drawPoint(pt)
 		}
+		mi.mu.Unlock()
 	}
 }
 
-func mandelbrotWorker(wg *sync.WaitGroup, pt Point, jobs chan Point, settings *Settings) {
-	defer wg.Done()
+// reportIterations logs how many iterations a render took, warning when it
+// went over Settings.IterationBudget.
+func reportIterations(total int64, settings *Settings) {
+	entry := log.WithFields(log.Fields{
+		"iterations": total,
+		"budget":     settings.IterationBudget,
+	})
+	if settings.IterationBudget > 0 && total > settings.IterationBudget {
+		entry.Warn("render went over the iteration budget; try enabling period detection or lowering MaxIterations")
+		return
+	}
+	entry.Debug("render finished")
+}
 
-	i := pt.X
-	j := pt.Y
+// renderFrame is what the render pool needs to know of a frame, shared by
+// all of its jobs. total is the iterations they took, first for the
+// alignment atomic needs, and pending counts the jobs not yet done.
+type renderFrame struct {
+	total    int64
+	ctx      context.Context
+	settings *Settings
+	kernel   Kernel
+	colors   *colorTable
+	mirror   bool
+	pending  sync.WaitGroup
+}
+
+// renderJob is a pixel of a frame for the render pool, tagged with the
+// render generation it belongs to. A job with no frame stops the worker
+// that takes it.
+type renderJob struct {
+	X, Y       float64
+	Generation int64
+	frame      *renderFrame
+}
 
-	x := mapToRange(float64(i), 0, settings.Width, settings.Min, settings.Max)
-	y := mapToRange(float64(j), 0, settings.Height, settings.Min, settings.Max)
+// sizePool starts or stops render pool workers until there are n.
+func (mi *MandelbrotImage) sizePool(n int) {
+	for ; mi.poolSize < n; mi.poolSize++ {
+		go mi.renderWorker()
+	}
+	for ; mi.poolSize > n; mi.poolSize-- {
+		mi.work <- renderJob{}
+	}
+}
+
+// renderWorker renders the pixels of whichever frames arrive on mi.work.
+func (mi *MandelbrotImage) renderWorker() {
+	for job := range mi.work {
+		if job.frame == nil {
+			return
+		}
+		mi.renderPixel(job)
+		job.frame.pending.Done()
+	}
+}
 
-	x = x - settings.Center.X
-	y = y - settings.Center.Y
+// renderPixel renders the pixel of job into mi, copying it over the rest
+// of its block when Settings.PixelBlock is above 1, and for a mirrored
+// frame the pixel mirroring it across the real axis too. Once the frame's
+// context is done or its generation superseded the job is dropped.
+func (mi *MandelbrotImage) renderPixel(job renderJob) {
+	frame := job.frame
+	if frame.ctx.Err() != nil || mi.superseded(job.Generation) {
+		return
+	}
+	settings := frame.settings
+	block := settings.pixelBlock()
 
-	x0 := x
-	y0 := y
+	i := job.X
+	j := job.Y
 
-	var iters int64
-	var z int64
-	for z = 0; z < settings.MaxIterations; z++ {
-		x1 := x*x - y*y
-		y1 := 2 * x * y
-		x = x1 + x0
-		y = y1 + y0
+	iters, nu := samplePixelSmooth(frame.kernel, i, j, settings)
+	atomic.AddInt64(&frame.total, iters)
+	red, green, blue := pixelColor(frame.colors, iters, nu, settings)
+	draw := func(x, y float64) {
+		pt := pixelPoint(x, y, red, green, blue, iters, settings)
+		pt.Smooth = nu
+		mi.DrawPoint(pt)
+	}
 
-		if x+y > 2 {
-			break
+	draw(i, j)
+	var dx, dy int64
+	for dy = 0; dy < block; dy++ {
+		for dx = 0; dx < block; dx++ {
+			x, y := i+float64(dx), j+float64(dy)
+			if (dx != 0 || dy != 0) && settings.inBounds(int(x), int(y)) {
+				draw(x, y)
+			}
 		}
-		iters += 1
 	}
+	if m, _ := mirrorRow(j, settings.Height); frame.mirror && mirroredRow(m, settings) {
+		atomic.AddInt64(&frame.total, iters)
+		draw(i, m)
+	}
+}
 
-	col := mapToRange(float64(iters), 0, float64(settings.MaxIterations), 0, 255)
-	if iters == settings.MaxIterations || col < 20 {
-		col = 0
+// startReplay loads the macro at path and starts replaying it.
+func startReplay(player *macroPlayer, path string) {
+	steps, err := readMacro(path)
+	if err != nil {
+		log.WithError(err).Error("could not load the macro")
+		return
+	}
+	player.Start(steps)
+	log.WithFields(log.Fields{"path": path, "keys": len(steps)}).Info("replaying the macro")
+}
+
+// jumpToRandomView moves the view to a random interesting location and
+// reports whether one was found.
+func jumpToRandomView(rng *rand.Rand, settings *Settings) bool {
+	kernel, err := kernelFor(settings)
+	if err != nil {
+		log.WithError(err).Error("could not set up the fractal kernel")
+		return false
 	}
 
-	red := mapToRange(col*col, 0, 255*255, 0, 255)
-	green := mapToRange(col/2, 0, 255/2, 0, 255)
-	blue := mapToRange(math.Sqrt(col), 0, math.Sqrt(255), 0, 255)
-	outpt := Point{
-		X:     i,
-		Y:     j,
-		Red:   uint8(red),
-		Green: uint8(green),
-		Blue:  uint8(blue),
+	re, im, span, ok := RandomView(rng, settings, kernel)
+	if !ok {
+		log.Info("no interesting random view found")
+		return false
 	}
-	jobs <- outpt
-	return
+
+	settings.CenterOn(re, im, span)
+	log.WithFields(log.Fields{"re": re, "im": im, "span": span}).Info("jumped to a random view")
+	return true
 }
 
 func main() {
+	canonical := flag.Bool("canonical", true, "start framed on the whole Mandelbrot set")
+	componentBailout := flag.Bool("component-bailout", false, "escape orbits when either component passes the bailout radius instead of the modulus: faster, but counts slightly more iterations")
+	keepView := flag.Bool("keep-view", false, "keep the current view when switching fractals rather than moving to the new fractal's home view")
+	kernel := flag.String("kernel", "", "fractal to render: mandelbrot, tricorn, fixed (fixed-point Mandelbrot) or formula")
+	julia := flag.Bool("julia", false, "draw the kernel's Julia set for the constant -julia-x + -julia-y i")
+	juliaX := flag.Float64("julia-x", defaultJulia.X, "real part of the Julia constant")
+	juliaY := flag.Float64("julia-y", defaultJulia.Y, "imaginary part of the Julia constant")
+	juliaStepFlag := flag.Float64("julia-step", juliaStep, "how far the arrow keys move the Julia constant in Julia mode")
+	formula := flag.String("formula", "", "iterate a custom formula in z and c, e.g. \"z*z*z + c\"")
+	iterationLimit := flag.Int64("iteration-limit", 100000, "upper bound for MaxIterations when adjusted with [ and ]")
+	diffMode := flag.Bool("diff", false, "compare two PNGs and write a diff image: -diff a.png b.png out.png")
+	tolerance := flag.Int("tolerance", 0, "largest per-channel difference -diff ignores")
+	random := flag.Bool("random", false, "start at a random view near the boundary of the set")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for random views")
+	iterationBudget := flag.Int64("iteration-budget", 100000000, "warn when a render takes more iterations than this in total; 0 to disable")
+	zoomIterations := flag.Int64("zoom-iterations", 5, "iterations + adds and - removes with each zoom step; 0 decouples them")
+	rampFrames := flag.Int64("startup-ramp", 4, "frames to reach MaxIterations over at startup; 0 renders it straight away")
+	dpiScale := flag.Float64("dpi-scale", 0, "render size multiplier for high-DPI displays; 0 detects it unless -render-width or -render-height is set")
+	renderWidth := flag.Int("render-width", 800, "width in pixels to compute the image at, independent of the window")
+	renderHeight := flag.Int("render-height", 800, "height in pixels to compute the image at, independent of the window")
+	windowWidth := flag.Int("window-width", 1280, "initial window width; the image is scaled to fit")
+	windowHeight := flag.Int("window-height", 720, "initial window height; the image is scaled to fit")
+	maxSpan := flag.Float64("max-span", 6, "widest span of the complex plane zooming out can reach; 0 for no limit")
+	renderTimeout := flag.Duration("render-timeout", 0, "give up on renders that take longer than this, e.g. 10s; 0 waits forever")
+	saveDir := flag.String("save-dir", ".", "directory the s key saves the displayed frame to as a PNG")
+	noSidecar := flag.Bool("no-sidecar", false, "don't write a .png.json sidecar of the settings next to each PNG the s key saves")
+	loadPath := flag.String("load", "", "start on the settings in a .png.json sidecar; overrides the other view and color flags")
+	renderWorkers := flag.Int("render-workers", 0, "goroutines to compute pixels on, in the window and headless; 0 for one per CPU")
+	interruptColumns := flag.Int64("interrupt-columns", 8, "columns of pixels between checks for input, which cuts a render short to be handled; 0 to always finish the frame")
+	iterations := flag.Int64("iterations", 200, "starting MaxIterations")
+	centerX := flag.Float64("center-x", 0, "Settings.Center.X, the real offset subtracted from mapped coordinates; overrides the starting view")
+	centerY := flag.Float64("center-y", 0, "Settings.Center.Y, the imaginary offset; overrides the starting view")
+	minView := flag.Float64("min", 0, "Settings.Min, the low end of the mapped range; overrides the starting view")
+	maxView := flag.Float64("max", 0, "Settings.Max, the high end of the mapped range; overrides the starting view")
+	zoomLevel := flag.Float64("zoom", 1, "magnification over the starting view, about its middle")
+	viewFlag := flag.String("view", "", "start on a shared view string, e.g. mandel:c=-0.743643+0.131825i&zoom=1e6&iter=2000; overrides the other view flags")
+	targetX := flag.Float64("target-x", 0, "real part of the point -target-zoom flies to")
+	targetY := flag.Float64("target-y", 0, "imaginary part of the point -target-zoom flies to")
+	targetZoom := flag.Float64("target-zoom", 0, "fly continuously from the starting view to this magnification about -target-x, -target-y; 0 to not")
+	autoZoomRate := flag.Float64("auto-zoom-rate", 2, "how many times closer the auto-zoom gets each second")
+	rotation := flag.Float64("rotation", 0, "turn the view by this many radians about its middle")
+	keyframesPath := flag.String("keyframes", "", "render the animation in this JSON keyframe file to PNG frames and exit")
+	framesDir := flag.String("frames-dir", "frames", "directory -keyframes writes its frames to")
+	fps := flag.Float64("fps", 30, "frame rate for -keyframes")
+	background := flag.String("background", "#000000", "color of pixels not yet rendered, as #rrggbb")
+	macroPath := flag.String("macro", "macro.txt", "file F5 records key presses to and F6 replays them from")
+	replay := flag.Bool("replay", false, "replay the -macro file at startup")
+	histogramPath := flag.String("histogram", "", "render the view and write a CSV histogram of its iteration counts to this path, then exit")
+	histogramBins := flag.Int64("histogram-bins", 0, "bins for -histogram; 0 for one per iteration count")
+	histogramMin := flag.Int64("histogram-min", 0, "lowest iteration count -histogram bins")
+	histogramMax := flag.Int64("histogram-max", 0, "highest iteration count -histogram bins; 0 for MaxIterations")
+	pixelAspect := flag.Float64("pixel-aspect", 1, "displayed pixel width over height, for non-square pixel targets")
+	previewFactor := flag.Float64("preview-factor", 0.25, "fraction of MaxIterations to render at while navigating; 0 or 1 to always render in full")
+	fastNavigation := flag.Bool("fast-navigation", true, "drop edge anti-aliasing, blur and relief shading while navigating")
+	previewIdle := flag.Duration("preview-idle", time.Second, "how long navigation must pause before the full render")
+	infoMode := flag.Bool("info", false, "print SDL, render driver and display details and exit")
+	zoomDuration := flag.Duration("smooth-zoom", 0, "how long to ease each + and - zoom over, e.g. 300ms; 0 zooms at once")
+	growthRate := flag.Float64("growth-rate", 30, "iterations per second the a key's animation climbs by")
+	jitter := flag.Bool("jitter", false, "jitter -keyframes frames by a per-frame sub-pixel offset to break up moire")
+	statsPath := flag.String("stats", "", "after headless renders, write JSON render statistics to this file, or - for stdout")
+	format := flag.String("format", "", "image format for exports: png, jpeg or bmp; empty goes by the file extension")
+	jpegQuality := flag.Int("jpeg-quality", 90, "JPEG export quality, 1 to 100")
+	bitDepth := flag.Int("bitdepth", 8, "bits per channel for exported images: 8, or 16 for PNG")
+	normalMapPath := flag.String("export-normalmap", "", "render the view and write a tangent-space normal map of its iteration height field to this path, then exit")
+	normalMapDirectX := flag.Bool("normalmap-directx", false, "write -export-normalmap with green pointing down, the DirectX convention, instead of up as in OpenGL")
+	passes := flag.Int64("passes", 1, "jittered passes to average each full render over, refining it progressively until input arrives")
+	interpolation := flag.String("interpolation", "default", "how gradient palettes blend between stops: default (the gradient's own), linear, smoothstep or catmull-rom")
+	alphaMode := flag.String("alpha", "opaque", "which pixels to make transparent in exports: opaque (none), interior or exterior")
+	areaSamples := flag.Int64("area-samples", 1000000, "random points k samples to estimate the area of the set in view")
+	overrides := flag.String("color-override", "", "color pixels of exact iteration counts ahead of the palette, as iterations=#rrggbb pairs separated by commas, e.g. 50=#ff0000")
+	colorOffset := flag.Float64("color-offset", 0, "shift the palette by this fraction of its length, wrapping around")
+	smooth := flag.Bool("smooth", false, "color by the normalized iteration count, which removes the color bands")
+	smoothBlend := flag.Float64("smooth-blend", 1, "how far -smooth smooths the colors, from 0 (banded) to 1 (fully smooth); 7 and 8 adjust it")
+	palette := flag.String("palette", "", "palette to color with: classic, grayscale or gold; empty for the default")
+	paletteFilePath := flag.String("palette-file", "", "color with the gradient in a GIMP .ggr file or a list of hex stops such as \"#000764 0%, #ffaa00 100%\", named after the file; f9 reloads it")
+	configPath := flag.String("config", defaultConfigPath(), "JSON file of preferences kept between runs; f8 saves the current palette to it as the default")
+	curve := flag.String("tone-curve", "", "output levels of the 5 tone curve points from black to white, e.g. 0,0.15,0.5,0.85,1; empty for none")
+	buddhabrotPath := flag.String("buddhabrot", "", "render the view as a Buddhabrot, the density of escaping orbits, to this image path and exit")
+	buddhabrotSamples := flag.Int64("buddhabrot-samples", 2000000, "random orbits -buddhabrot and -nebulabrot trace")
+	sampling := flag.String("buddhabrot-sampling", "uniform", "how -buddhabrot and -nebulabrot pick orbits: uniform, grid (stratified, less noise) or metropolis (converges much faster on zoomed views)")
+	nebulabrotPath := flag.String("nebulabrot", "", "render the view as a Nebulabrot, a Buddhabrot at three iteration limits as red, green and blue, to this image path and exit")
+	nebulabrotLimits := flag.String("nebulabrot-iterations", "5000,500,50", "red, green and blue iteration limits for -nebulabrot, as r,g,b")
+	sheetPath := flag.String("contact-sheet", "", "render a grid of the view with one setting swept across it to this image path and exit")
+	sheetParam := flag.String("sheet-param", "iterations", "setting -contact-sheet sweeps: iterations, color-density, color-offset, zoom or rotation")
+	sheetFrom := flag.Float64("sheet-from", 50, "value of the swept setting in the first contact sheet cell")
+	sheetTo := flag.Float64("sheet-to", 800, "value of the swept setting in the last contact sheet cell")
+	sheetGrid := flag.String("sheet-grid", "3x3", "contact sheet cells across and down, as colsxrows")
+	roiFlag := flag.String("roi", "", "render only the region x,y,w,h of the image, in pixels at -render-width by -render-height, for profiling")
+	stripPath := flag.String("palette-strip", "", "write the palette as a 256x32 gradient PNG to this path and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), exitStatusHelp)
+	}
+	flag.Parse()
+
+	if *diffMode {
+		os.Exit(runDiff(flag.Args(), *tolerance))
+	}
+	if *infoMode {
+		os.Exit(runInfo())
+	}
+
 	log.SetFormatter(&log.JSONFormatter{})
-	log.SetOutput(os.Stdout)
+	log.SetOutput(logOutput(*statsPath))
 	log.SetLevel(log.DebugLevel)
 
-	err := sdl.Init(sdl.INIT_EVERYTHING)
+	settings := Settings{
+		Width:          float64(*renderWidth),
+		Height:         float64(*renderHeight),
+		MaxIterations:  *iterations,
+		VarianceWindow: 40,
+
+		PeriodDetection:  true,
+		ComponentBailout: *componentBailout,
+		IterationStep:    25,
+		IterationLimit:   *iterationLimit,
+		IterationBudget:  *iterationBudget,
+
+		IterationsPerZoomStep: *zoomIterations,
+
+		AASamples:     3,
+		EdgeThreshold: 1,
+		DetailRegion:  96,
+		DetailSamples: 8,
+
+		ColorDensity: 1,
+		ColorOffset:  *colorOffset,
+		ZoomFactor:   1.25,
+		PixelAspect:  *pixelAspect,
+		Rotation:     *rotation,
+
+		SmoothColoring:  *smooth,
+		SmoothBlend:     *smoothBlend,
+		AnimationJitter: *jitter,
+		ContourSpacing:  10,
+		LightAzimuth:    135,
+		LightElevation:  45,
+		MaxSpan:         *maxSpan,
+
+		RenderTimeout: *renderTimeout,
+		Workers:       *renderWorkers,
+		MaxPasses:     *passes,
+		KeepView:      *keepView,
+
+		Julia:     Point{X: *juliaX, Y: *juliaY},
+		JuliaStep: *juliaStepFlag,
+	}
+	settings.Kernel = *kernel
+	if *formula != "" {
+		settings.Kernel = "formula"
+		settings.Formula = *formula
+	}
+	home, err := registrationFor(&settings)
 	if err != nil {
-		log.WithError(err).Panic("could not init SDL2")
+		fail(exitUsage, err, "invalid fractal settings")
+	}
+	// only the Mandelbrot set has the legacy framing
+	if *canonical || home.Name != kernels[0].Name {
+		settings.ApplyView(home.Home)
+	} else {
+		settings.ApplyView(legacyView)
+	}
+	settings.MaxIterations = home.Iterations
+	if *julia {
+		settings.Fractal = FractalJulia
+		settings.ApplyView(juliaView)
+	}
+	explicitSize := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "iterations":
+			settings.MaxIterations = *iterations
+		case "render-width", "render-height":
+			explicitSize = true
+		case "center-x":
+			settings.Center.X = *centerX
+		case "center-y":
+			settings.Center.Y = *centerY
+		case "min":
+			settings.Min = *minView
+		case "max":
+			settings.Max = *maxView
+		}
+	})
+	if _, err := kernelFor(&settings); err != nil {
+		fail(exitUsage, err, "invalid fractal settings")
+	}
+	settings.InitialSpan = settings.Max - settings.Min
+	if *smoothBlend < 0 || *smoothBlend > 1 {
+		fail(exitUsage, errors.Errorf("got %g", *smoothBlend), "the smooth blend must be between 0 and 1")
+	}
+	if *zoomLevel <= 0 {
+		fail(exitUsage, errors.Errorf("got %g", *zoomLevel), "the zoom must be positive")
+	}
+	if *zoomLevel != 1 {
+		re, im := settings.ViewCenter()
+		settings.SetView(complex(re, im), *zoomLevel)
+	}
+	if *viewFlag != "" {
+		shared, err := ParseViewString(*viewFlag)
+		if err == nil {
+			err = shared.Apply(&settings)
+		}
+		if err != nil {
+			fail(exitUsage, err, "invalid view string")
+		}
 	}
-	defer sdl.Quit()
 
-	settings := Settings{
-		Width:         800,
-		Height:        800,
-		Min:           -2.84,
-		Max:           2.0,
-		MaxIterations: 200,
-		Center: Point{
-			X: 0.5,
-			Y: 0.0,
-		},
+	bg, err := parseHexColor(*background)
+	if err != nil {
+		fail(exitUsage, err, "invalid background color")
+	}
+	settings.BackgroundColor = bg
+
+	settings.ColorOverrides, err = parseColorOverrides(*overrides)
+	if err != nil {
+		fail(exitUsage, err, "invalid color override")
+	}
+
+	var filePalette *paletteFile
+	if *paletteFilePath != "" {
+		name, f, err := registerPaletteFile(*paletteFilePath)
+		if err != nil {
+			fail(exitCodeFor(err), err, "could not load the palette file")
+		}
+		filePalette = f
+		if *palette == "" {
+			*palette = name
+		}
+	}
+	settings.Palette, err = parsePalette(*palette)
+	if err != nil {
+		fail(exitUsage, err, "invalid palette")
+	}
+
+	settings.ToneCurve, err = parseToneCurve(*curve)
+	if err != nil {
+		fail(exitUsage, err, "invalid tone curve")
 	}
 
+	settings.AlphaMode, err = parseAlphaMode(*alphaMode)
+	if err != nil {
+		fail(exitUsage, err, "invalid alpha mode")
+	}
+
+	settings.Interpolation, err = parseInterpolation(*interpolation)
+	if err != nil {
+		fail(exitUsage, err, "invalid interpolation")
+	}
+
+	// the saved default palette applies unless the flags choose one; f7
+	// resets to it
+	prefs, err := readConfig(*configPath)
+	if err != nil {
+		fail(exitCodeFor(err), err, "could not read the config")
+	}
+	var startPalette Settings
+	if err := prefs.ApplyPalette(&startPalette); err != nil {
+		fail(exitUsage, err, "invalid config")
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "palette", "palette-file":
+			startPalette.Palette = settings.Palette
+		case "interpolation":
+			startPalette.Interpolation = settings.Interpolation
+		}
+	})
+	settings.Palette = startPalette.Palette
+	settings.Interpolation = startPalette.Interpolation
+
+	if *loadPath != "" {
+		settings, err = readSidecar(*loadPath)
+		if err != nil {
+			fail(exitCodeFor(err), err, "could not load the sidecar")
+		}
+		explicitSize = true
+	}
+
+	stats, closeStats, err := openStats(*statsPath)
+	if err != nil {
+		fail(exitIO, err, "could not open the statistics file")
+	}
+	defer closeStats()
+
+	if *roiFlag != "" {
+		roi, err := parseROI(*roiFlag)
+		if err != nil {
+			fail(exitUsage, err, "invalid region of interest")
+		}
+		if !roi.In(image.Rect(0, 0, int(settings.Width), int(settings.Height))) {
+			fail(exitUsage, errors.Errorf("%v is outside the %vx%v image", roi, settings.Width, settings.Height), "invalid region of interest")
+		}
+		settings.ROI = roi
+	}
+
+	export := exportOptions{Format: *format, Quality: *jpegQuality, BitDepth: *bitDepth}
+	if *bitDepth != 8 && *bitDepth != 16 {
+		fail(exitUsage, errors.Errorf("got %d", *bitDepth), "the bit depth must be 8 or 16")
+	}
+	if *jpegQuality < 1 || *jpegQuality > 100 {
+		fail(exitUsage, errors.Errorf("got %d", *jpegQuality), "JPEG quality must be between 1 and 100")
+	}
+	if *renderWidth < 1 || *renderHeight < 1 {
+		fail(exitUsage, errors.Errorf("got %dx%d", *renderWidth, *renderHeight), "the render size must be at least 1x1")
+	}
+	if *windowWidth < 1 || *windowHeight < 1 {
+		fail(exitUsage, errors.Errorf("got %dx%d", *windowWidth, *windowHeight), "the window size must be at least 1x1")
+	}
+	if *growthRate <= 0 {
+		fail(exitUsage, errors.Errorf("got %g", *growthRate), "the growth rate must be positive")
+	}
+	if *juliaStepFlag <= 0 {
+		fail(exitUsage, errors.Errorf("got %g", *juliaStepFlag), "the Julia step must be positive")
+	}
+	if *targetZoom < 0 {
+		fail(exitUsage, errors.Errorf("got %g", *targetZoom), "the target zoom can't be negative")
+	}
+	if *autoZoomRate <= 1 {
+		fail(exitUsage, errors.Errorf("got %g", *autoZoomRate), "the auto-zoom rate must be above 1")
+	}
+	if *renderWorkers < 0 {
+		fail(exitUsage, errors.Errorf("got %d", *renderWorkers), "the render worker count can't be negative")
+	}
+	if *interruptColumns < 0 {
+		fail(exitUsage, errors.Errorf("got %d", *interruptColumns), "the interrupt column count can't be negative")
+	}
+	if *areaSamples < 1 {
+		fail(exitUsage, errors.Errorf("got %d", *areaSamples), "the area estimate needs at least one sample")
+	}
+	if *buddhabrotSamples < 1 {
+		fail(exitUsage, errors.Errorf("got %d", *buddhabrotSamples), "the Buddhabrot needs at least one sample")
+	}
+	strategy, err := parseSamplingStrategy(*sampling)
+	if err != nil {
+		fail(exitUsage, err, "invalid Buddhabrot sampling")
+	}
+
+	if *keyframesPath != "" {
+		finishHeadless("could not render the animation", closeStats, func() error {
+			return runKeyframes(*keyframesPath, *framesDir, *fps, &settings, export, stats)
+		})
+	}
+
+	if *histogramPath != "" {
+		finishHeadless("could not write the histogram", closeStats, func() error {
+			return runHistogram(*histogramPath, *histogramBins, *histogramMin, *histogramMax, &settings, stats)
+		})
+	}
+
+	if *normalMapPath != "" {
+		finishHeadless("could not export the normal map", closeStats, func() error {
+			return runNormalMap(*normalMapPath, *normalMapDirectX, &settings, export, stats)
+		})
+	}
+
+	if *buddhabrotPath != "" {
+		finishHeadless("could not render the Buddhabrot", closeStats, func() error {
+			return runBuddhabrot(*buddhabrotPath, *buddhabrotSamples, strategy, *seed, &settings, export)
+		})
+	}
+
+	if *nebulabrotPath != "" {
+		limits, err := parseNebulabrotLimits(*nebulabrotLimits)
+		if err != nil {
+			fail(exitUsage, err, "invalid Nebulabrot iteration limits")
+		}
+		finishHeadless("could not render the Nebulabrot", closeStats, func() error {
+			return runNebulabrot(*nebulabrotPath, *buddhabrotSamples, limits, strategy, *seed, &settings, export)
+		})
+	}
+
+	if *sheetPath != "" {
+		var cols, rows int
+		if _, err := fmt.Sscanf(*sheetGrid, "%dx%d", &cols, &rows); err != nil {
+			fail(exitUsage, errors.Wrapf(err, "%q is not colsxrows", *sheetGrid), "invalid contact sheet grid")
+		}
+		finishHeadless("could not render the contact sheet", closeStats, func() error {
+			return runContactSheet(*sheetPath, *sheetParam, *sheetFrom, *sheetTo, cols, rows, &settings, export, stats)
+		})
+	}
+
+	if *stripPath != "" {
+		finishHeadless("could not export the palette", closeStats, func() error {
+			return writeImage(*stripPath, paletteStrip(&settings, 256, 32, *bitDepth == 16), export)
+		})
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	if *random {
+		jumpToRandomView(rng, &settings)
+	}
+
+	err = sdl.Init(sdl.INIT_EVERYTHING)
+	if err != nil {
+		log.WithError(err).Panic("could not init SDL2")
+	}
+	defer sdl.Quit()
+
 	window, err := sdl.CreateWindow("Mandelbrot Set",
 		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		1280, 720, sdl.WINDOW_SHOWN)
+		int32(*windowWidth), int32(*windowHeight), sdl.WINDOW_SHOWN|sdl.WINDOW_ALLOW_HIGHDPI|sdl.WINDOW_RESIZABLE)
 	if err != nil {
 		log.WithError(err).Panic("error creating a window")
 	}
@@ -195,87 +1186,733 @@ func main() {
 	}
 	defer renderer.Destroy()
 
+	// Render at the drawable's real resolution unless the render size was
+	// given. The logical size below then maps mouse coordinates onto image
+	// pixels for us, however the window is sized.
+	scale := *dpiScale
+	if scale <= 0 && explicitSize {
+		scale = 1
+	} else if scale <= 0 {
+		scale, err = displayScale(window, renderer)
+		if err != nil {
+			log.WithError(err).Warn("could not query the display scale")
+		}
+	}
+	settings.Width *= scale
+	settings.Height *= scale
+	settings.ROI = image.Rect(
+		int(float64(settings.ROI.Min.X)*scale), int(float64(settings.ROI.Min.Y)*scale),
+		int(float64(settings.ROI.Max.X)*scale), int(float64(settings.ROI.Max.Y)*scale))
+	log.WithFields(log.Fields{
+		"scale":  scale,
+		"width":  settings.Width,
+		"height": settings.Height,
+	}).Info("render size")
+
 	err = renderer.SetLogicalSize(int32(settings.Width), int32(settings.Height))
 	if err != nil {
 		log.WithError(err).Panic("error setting logical size on the renderer")
 	}
 
-	texture, err := renderer.CreateTexture(
-		sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC,
-		int32(settings.Width), int32(settings.Height))
+	err = checkTextureSize(renderer, int32(settings.Width), int32(settings.Height))
+	if err != nil {
+		log.WithError(err).Fatal("the image doesn't fit in a texture; try a smaller size or -dpi-scale 1")
+	}
+	initText(renderer)
+
+	texture, err := newFrameTexture(renderer, int32(settings.Width), int32(settings.Height))
 	if err != nil {
 		log.WithError(err).Panic("error creating a texture on the renderer")
 	}
 	defer texture.Destroy()
 
 	mandelbrotImg := NewMandelbrotImage(settings.Width, settings.Height, &settings)
+	mandelbrotImg.Workers = *renderWorkers
+	if *interruptColumns > 0 {
+		mandelbrotImg.Interrupt = inputPending
+		mandelbrotImg.InterruptEvery = *interruptColumns
+	}
 	defer mandelbrotImg.Close()
 
 	go imageWriter(mandelbrotImg, mandelbrotImg.Jobs)
 
+	ramp := newStartupRamp(&settings, *rampFrames)
 	mandelbrotImg.Init()
 	mandelbrotImg.ForceRender()
 
+	keys := defaultKeys
+	var measure measureTool
+	growth := iterationAnimation{Rate: *growthRate}
+	var zoom zoomAnimation
+	var juliaMode juliaToggle
+	autoZoom := autoZoom{Rate: *autoZoomRate}
+	if *targetZoom > 0 {
+		target := settings.Clone()
+		target.SetView(complex(*targetX, *targetY), *targetZoom)
+		autoZoom.SetTargetAt(*targetX, *targetY, target.Max-target.Min, 0)
+		autoZoom.Toggle(&settings)
+	}
+	var grid gridOverlay
+	var tour locationTour
+	var namedJulia juliaTour
+	var probe periodProbe
+	var detail detailBoost
+	area := newAreaEstimate(rng, *areaSamples)
+	recorder := macroRecorder{path: *macroPath}
+	var player macroPlayer
+	if *replay {
+		startReplay(&player, *macroPath)
+	}
+	panel := newParamPanel()
+	var lastPalettes paletteSwap
+	lastPalettes.Track(&settings)
+
 	running := true
 	updateTexture := false
+	recolor := false
+	paused := false
+	// while the view is moving, renders are cheap previews; a full render
+	// follows once navigation has been idle for previewIdle
+	var lastNavigation time.Time
+	previewPending := false
+	precisionExhausted := false
+	frozen := false
+	// pixel doubling makes navigation renders compute one pixel per 2x2
+	// block until navigation settles
+	doubling := false
+	// wheel events carry no position, so the cursor is tracked from motion
+	cursorX, cursorY := settings.Width/2, settings.Height/2
+	// saves encode in the background and report here
+	saves := make(chan savedPNG, 4)
+	pendingSaves := 0
+	var savedNotice string
+	var savedUntil time.Time
+	reportSave := func(saved savedPNG) {
+		pendingSaves--
+		if saved.Err != nil {
+			log.WithError(saved.Err).Error("could not save the image")
+		} else {
+			log.WithField("path", saved.Path).Info("saved the image")
+		}
+		savedNotice = saved.Notice()
+		savedUntil = time.Now().Add(savedNoticeDuration)
+	}
+	lastFrame := time.Now()
 	for running {
+		select {
+		case saved := <-saves:
+			reportSave(saved)
+		default:
+		}
+		if err := player.Inject(); err != nil {
+			log.WithError(err).Error("could not replay the macro")
+		}
+
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			before := viewStateOf(&settings)
 			switch t := event.(type) {
 			case *sdl.QuitEvent:
 				running = false
 			case *sdl.KeyboardEvent:
+				if t.Type != sdl.KEYDOWN {
+					break
+				}
 				keyCode := t.Keysym.Sym
+				mandelbrotImg.StopPasses()
 
-				if keyCode == 113 {
+				// record and replay key presses
+				if keyCode == sdl.K_F5 {
+					recorder.Toggle()
+					break
+				}
+				if keyCode == sdl.K_F6 {
+					startReplay(&player, *macroPath)
+					break
+				}
+				recorder.Record(t.Keysym)
+
+				// reset the palette to the saved default, or save the
+				// current one as the default
+				if keyCode == sdl.K_F7 {
+					if err := prefs.ApplyPalette(&settings); err != nil {
+						log.WithError(err).Error("could not reset the palette")
+					} else {
+						log.WithField("palette", paletteName(&settings)).Info("reset the palette")
+						recolor = true
+					}
+				}
+				if keyCode == sdl.K_F8 {
+					saved := paletteConfig(&settings)
+					if err := writeConfig(*configPath, saved); err != nil {
+						log.WithError(err).Error("could not save the default palette")
+					} else {
+						prefs = saved
+						log.WithFields(log.Fields{"palette": saved.Palette, "config": *configPath}).Info("saved the default palette")
+					}
+				}
+
+				// pick up edits to the palette file
+				if keyCode == sdl.K_F9 && filePalette != nil {
+					if err := filePalette.Reload(); err != nil {
+						log.WithError(err).Error("could not reload the palette")
+					} else {
+						log.WithField("path", *paletteFilePath).Info("reloaded the palette")
+						recolor = true
+					}
+				}
+
+				if keyCode == sdl.K_TAB {
+					panel.Toggle()
+				}
+				if panel.Visible {
+					if handled, rerender, recolorOnly := panel.HandleKey(keyCode, &settings); handled {
+						updateTexture = updateTexture || rerender
+						recolor = recolor || recolorOnly
+						break
+					}
+				}
+
+				if matches(keyCode, keys.Quit) {
 					running = false
 				}
 
+				// in Julia mode the arrow keys explore the family by moving
+				// the constant instead
+				if settings.Fractal == FractalJulia {
+					dx, dy := 0.0, 0.0
+					switch keyCode {
+					case sdl.K_LEFT:
+						dx = -1
+					case sdl.K_RIGHT:
+						dx = 1
+					case sdl.K_DOWN:
+						dy = -1
+					case sdl.K_UP:
+						dy = 1
+					}
+					if dx != 0 || dy != 0 {
+						settings.MoveJulia(dx, dy)
+						updateTexture = true
+						lastNavigation = time.Now()
+						break
+					}
+				}
+
 				// move the set in x and y
 				if keyCode == sdl.K_LEFT {
 					settings.Center.X -= 0.05
 					updateTexture = true
+					lastNavigation = time.Now()
 				}
 				if keyCode == sdl.K_RIGHT {
 					settings.Center.X += 0.05
 					updateTexture = true
+					lastNavigation = time.Now()
 				}
 				if keyCode == sdl.K_DOWN {
 					settings.Center.Y += 0.05
 					updateTexture = true
+					lastNavigation = time.Now()
 				}
 				if keyCode == sdl.K_UP {
 					settings.Center.Y -= 0.05
 					updateTexture = true
+					lastNavigation = time.Now()
 				}
 
 				// zoom in and out
 				if keyCode == sdl.K_EQUALS {
-					settings.Min += 0.15
-					settings.Max -= 0.1
-					settings.MaxIterations += 5
+					zoom.Zoom(&settings, true, *zoomDuration)
+					settings.AdjustIterations(settings.IterationsPerZoomStep)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
+				if keyCode == sdl.K_MINUS && zoom.Zoom(&settings, false, *zoomDuration) {
+					settings.AdjustIterations(-settings.IterationsPerZoomStep)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
+
+				// freeze the image to study it undisturbed
+				if keyCode == sdl.K_f {
+					frozen = !frozen
+				}
+
+				// rotate the view
+				if keyCode == sdl.K_SEMICOLON {
+					settings.Rotate(-rotationStep)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
+				if keyCode == sdl.K_QUOTE {
+					settings.Rotate(rotationStep)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
+
+				// fine or coarse zoom steps
+				if keyCode == sdl.K_COMMA {
+					settings.StepZoomFactor(-1)
+				}
+				if keyCode == sdl.K_PERIOD {
+					settings.StepZoomFactor(1)
+				}
+
+				// change the iteration count without zooming
+				if keyCode == sdl.K_LEFTBRACKET {
+					updateTexture = settings.AdjustIterations(-settings.IterationStep) || updateTexture
+				}
+				if keyCode == sdl.K_RIGHTBRACKET {
+					updateTexture = settings.AdjustIterations(settings.IterationStep) || updateTexture
+				}
+
+				if keyCode == sdl.K_g {
+					grid.Toggle()
+				}
+
+				if keyCode == sdl.K_h {
+					settings.Relief = !settings.Relief
+				}
+
+				if keyCode == sdl.K_n {
+					settings.Contours = !settings.Contours
+				}
+
+				// tighter or looser color bands
+				if keyCode == sdl.K_0 {
+					recolor = settings.AdjustColorDensity(1) || recolor
+				}
+				if keyCode == sdl.K_9 {
+					recolor = settings.AdjustColorDensity(-1) || recolor
+				}
+
+				// shift the palette along the iteration range
+				if keyCode == sdl.K_PAGEUP {
+					settings.ShiftColorOffset(1)
+					recolor = true
+				}
+				if keyCode == sdl.K_PAGEDOWN {
+					settings.ShiftColorOffset(-1)
+					recolor = true
+				}
+
+				if keyCode == sdl.K_i {
+					settings.Invert = !settings.Invert
+					recolor = true
+				}
+
+				// blend between the bands and smooth colors; the normalized
+				// counts are cached, so only the colors change
+				if keyCode == sdl.K_8 {
+					recolor = settings.AdjustSmoothBlend(1) || recolor
+				}
+				if keyCode == sdl.K_7 {
+					recolor = settings.AdjustSmoothBlend(-1) || recolor
+				}
+
+				// smooth out the color bands; orbits are followed further out
+				// for it, so the view is rendered again
+				if keyCode == sdl.K_BACKSLASH {
+					settings.SmoothColoring = !settings.SmoothColoring
 					updateTexture = true
 				}
-				if keyCode == sdl.K_MINUS {
-					settings.Min -= 0.15
-					settings.Max += 0.1
-					settings.MaxIterations -= 5
+
+				// switch between the fractal and its Julia sets
+				if keyCode == sdl.K_j && t.Keysym.Mod&sdl.KMOD_SHIFT == 0 {
+					juliaMode.Toggle(&settings)
 					updateTexture = true
 				}
+
+				// step through the named Julia constants
+				if keyCode == sdl.K_j && t.Keysym.Mod&sdl.KMOD_SHIFT != 0 && settings.Fractal == FractalJulia {
+					namedJulia.Next(&settings)
+					updateTexture = true
+				}
+
+				if keyCode == sdl.K_c && t.Keysym.Mod&sdl.KMOD_SHIFT == 0 {
+					cyclePalette(&settings, 1)
+					log.WithField("palette", paletteName(&settings)).Info("switched palette")
+					recolor = true
+				}
+
+				// flip back to the palette used before this one; only the
+				// colors change, so the cached iterations are recolored
+				if keyCode == sdl.K_c && t.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+					if lastPalettes.Swap(&settings) {
+						log.WithField("palette", paletteName(&settings)).Info("swapped palette")
+						recolor = true
+					}
+				}
+
+				if keyCode == sdl.K_o {
+					settings.ToneMap = (settings.ToneMap + 1) % toneMapCount
+					recolor = true
+				}
+
+				if keyCode == sdl.K_d {
+					settings.Dither = !settings.Dither
+					recolor = true
+				}
+
+				// zoom towards the most detailed part of the view
+				if keyCode == sdl.K_e {
+					if px, py, ok := mandelbrotImg.MostDetailedRegion(); ok {
+						re, im := settings.PixelToComplex(px, py)
+						settings.ZoomTo(re, im, 0.5)
+						updateTexture = true
+					}
+				}
+
+				// switch between the Mandelbrot set and the Tricorn
+				if keyCode == sdl.K_t {
+					next := "tricorn"
+					if settings.Kernel == "tricorn" {
+						next = "mandelbrot"
+					}
+					if err := switchKernel(&settings, next); err != nil {
+						log.WithError(err).Error("could not switch fractal")
+					}
+					updateTexture = true
+				}
+
+				// animate the iteration count growing up to the current one
+				if keyCode == sdl.K_a {
+					growth.Toggle(&settings)
+					updateTexture = true
+				}
+
+				if keyCode == sdl.K_r {
+					updateTexture = jumpToRandomView(rng, &settings) || updateTexture
+				}
+
+				// copy the view as a view string to share with shift+y
+				if keyCode == sdl.K_y && t.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+					text, err := ViewString(&settings)
+					if err == nil {
+						err = sdl.SetClipboardText(text)
+					}
+					if err != nil {
+						log.WithError(err).Error("could not copy the view string")
+					} else {
+						log.WithField("view", text).Info("copied the view string to the clipboard")
+					}
+				}
+
+				// copy the view's coordinates to the clipboard
+				if keyCode == sdl.K_y && t.Keysym.Mod&sdl.KMOD_SHIFT == 0 {
+					coords := viewCoordinates(&settings)
+					if err := sdl.SetClipboardText(coords); err != nil {
+						log.WithError(err).Error("could not copy the coordinates")
+					} else {
+						log.WithField("coordinates", coords).Info("copied the coordinates to the clipboard")
+					}
+				}
+
+				// fly continuously to a target view: z makes the current
+				// view the target and space starts or stops the flight
+				if keyCode == sdl.K_z {
+					autoZoom.SetTarget(&settings)
+					log.Info("set the auto-zoom target to the current view")
+				}
+				if keyCode == sdl.K_SPACE {
+					if autoZoom.Toggle(&settings) {
+						log.Info("auto-zooming to the target")
+					} else {
+						log.Info("auto-zoom stopped")
+					}
+				}
+
+				// toggle pixel doubling while navigating
+				if keyCode == sdl.K_v {
+					doubling = !doubling
+					log.WithField("doubling", doubling).Info("toggled pixel doubling")
+				}
+
+				// save the displayed image
+				if keyCode == sdl.K_s {
+					sidecar := &settings
+					if *noSidecar {
+						sidecar = nil
+					}
+					mandelbrotImg.SavePNGAsync(savePath(*saveDir, time.Now()), sidecar, saves)
+					pendingSaves++
+				}
+
+				// copy the displayed image to the clipboard
+				if keyCode == sdl.K_w {
+					copyFrame(mandelbrotImg, &settings)
+				}
+
+				// print the view's parameters to relaunch it
+				if keyCode == sdl.K_p {
+					params := paramsFor(&settings)
+					blob, err := params.JSON()
+					if err != nil {
+						log.WithError(err).Error("could not encode the view parameters")
+					}
+					fmt.Println(params.CommandLine())
+					fmt.Println(blob)
+				}
+
+				// cycle through famous locations
+				if keyCode == sdl.K_l {
+					tour.Next(&settings)
+					updateTexture = true
+				}
+
+				// measure the distance between two clicked points
+				if keyCode == sdl.K_m {
+					measure.Toggle()
+				}
+				if keyCode == sdl.K_x {
+					measure.Clear()
+				}
+
+				// show the period of the orbit under clicked points
+				if keyCode == sdl.K_b {
+					probe.Toggle()
+				}
+
+				// supersample the region around clicked points
+				if keyCode == sdl.K_u {
+					detail.Toggle()
+				}
+
+				// estimate the area of the set in view
+				if keyCode == sdl.K_k {
+					area.Toggle(&settings)
+				}
+			case *sdl.WindowEvent:
+				switch t.Event {
+				case sdl.WINDOWEVENT_FOCUS_LOST, sdl.WINDOWEVENT_MINIMIZED:
+					if !paused {
+						paused = true
+						updateTexture = mandelbrotImg.Cancel() || updateTexture
+						mandelbrotImg.StopPasses()
+						log.Debug("window hidden; rendering paused")
+					}
+				case sdl.WINDOWEVENT_FOCUS_GAINED, sdl.WINDOWEVENT_RESTORED:
+					if paused {
+						paused = false
+						log.Debug("window shown; rendering resumed")
+					}
+				}
+			case *sdl.MouseMotionEvent:
+				cursorX, cursorY = float64(t.X), float64(t.Y)
+			case *sdl.MouseWheelEvent:
+				ticks := t.Y
+				if t.Direction == sdl.MOUSEWHEEL_FLIPPED {
+					ticks = -ticks
+				}
+				// zoom about the cursor, keeping the point under it fixed
+				factor, iterations := wheelZoomFactor, settings.IterationsPerZoomStep
+				if ticks < 0 {
+					ticks, factor, iterations = -ticks, 1/wheelZoomFactor, -iterations
+				}
+				for ; ticks > 0; ticks-- {
+					if !settings.ZoomAt(cursorX, cursorY, factor) {
+						break
+					}
+					settings.AdjustIterations(iterations)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
+			case *sdl.MouseButtonEvent:
+				if t.Type != sdl.MOUSEBUTTONDOWN || t.Button != sdl.BUTTON_LEFT {
+					break
+				}
+				mandelbrotImg.StopPasses()
+
+				if measure.Active {
+					measure.Place(float64(t.X), float64(t.Y), &settings)
+				}
+				if probe.Active {
+					probe.Probe(float64(t.X), float64(t.Y), &settings)
+				}
+				if detail.Active {
+					detail.Boost(mandelbrotImg, int(t.X), int(t.Y))
+				}
+				// without a tool to click for, a click recenters the view
+				if !measure.Active && !probe.Active && !detail.Active {
+					re, im := settings.PixelToComplex(float64(t.X), float64(t.Y))
+					settings.CenterOn(re, im, settings.Max-settings.Min)
+					updateTexture = true
+					lastNavigation = time.Now()
+				}
 			}
+			traceViewChange(before, &settings, eventAction(event))
+			lastPalettes.Track(&settings)
 		}
 
-		texture.Update(nil, mandelbrotImg.Pixels[:], 3200)
-		window.UpdateSurface()
+		if paused {
+			// animations hold still while paused
+			lastFrame = time.Now()
+			sdl.Delay(500)
+			continue
+		}
+
+		animating := false
+		if frozen {
+			// the texture keeps showing the frozen frame; nothing renders
+			// or animates, and changes made meanwhile apply once unfrozen
+			lastFrame = time.Now()
+		} else {
+			// previews while navigating skip the costly post-processing too
+			fast := previewPending && *fastNavigation
+			// the renderer presents the frame; the window surface API can't
+			// be combined with it
+			texture.Update(mandelbrotImg.Displayed(fast))
 
-		if updateTexture {
-			mandelbrotImg.ForceRender()
-			updateTexture = false
+			// animations advance by wall-clock time, whatever the frame rate
+			now := time.Now()
+			dt := now.Sub(lastFrame)
+			lastFrame = now
+			// every animation sees every dt, so none loses time to another
+			stepped := ramp.Step(&settings)
+			stepped = growth.Step(&settings, dt) || stepped
+			stepped = zoom.Step(&settings, dt) || stepped
+			stepped = autoZoom.Step(&settings, dt) || stepped
+			if stepped {
+				updateTexture = true
+			}
+			animating = stepped || zoom.Active || growth.Active || autoZoom.Active || player.Playing()
+			area.Step(&settings)
+
+			// a frame cut short for input is rendered again once the input
+			// is handled
+			if mandelbrotImg.Interrupted() {
+				updateTexture = true
+			}
+			if updateTexture {
+				exhausted := PrecisionExhausted(settings)
+				if exhausted && !precisionExhausted {
+					re, im := settings.ViewCenter()
+					log.WithFields(log.Fields{
+						"re":   re,
+						"im":   im,
+						"span": settings.Max - settings.Min,
+					}).Warn("float64 precision exhausted at this zoom; a high-precision mode is needed to go deeper")
+				}
+				precisionExhausted = exhausted
+
+				reduced := *previewFactor > 0 && *previewFactor < 1
+				if (reduced || *fastNavigation || doubling) && time.Since(lastNavigation) < *previewIdle {
+					factor := 1.0
+					if reduced {
+						factor = *previewFactor
+					}
+					var block int64 = 1
+					if doubling {
+						block = 2
+					}
+					mandelbrotImg.ForcePreview(factor, *fastNavigation, block)
+					previewPending = true
+				} else {
+					mandelbrotImg.ForceRender()
+					previewPending = false
+				}
+				updateTexture = false
+				recolor = false
+				detail.Clear()
+			} else if previewPending && time.Since(lastNavigation) >= *previewIdle {
+				mandelbrotImg.ForceRender()
+				previewPending = false
+			}
+			// a render in progress colors its pixels as it goes, so recoloring
+			// waits for it to finish rather than racing it
+			if recolor && !mandelbrotImg.Rendering() {
+				mandelbrotImg.Recolor()
+				recolor = false
+			}
 		}
 
 		renderer.Clear()
-		renderer.Copy(texture, nil, nil)
+		err = renderer.Copy(texture.Texture, nil, nil)
+		if err != nil {
+			log.WithError(err).Error("error copying the frame to the renderer")
+		}
+
+		err = grid.Draw(renderer, &settings)
+		if err != nil {
+			log.WithError(err).Error("error drawing the grid overlay")
+		}
+		err = measure.Draw(renderer)
+		if err != nil {
+			log.WithError(err).Error("error drawing the measurement overlay")
+		}
+		err = detail.Draw(renderer)
+		if err != nil {
+			log.WithError(err).Error("error drawing the boosted regions")
+		}
+		err = probe.Draw(renderer)
+		if err != nil {
+			log.WithError(err).Error("error drawing the period probe")
+		}
+		err = panel.Draw(renderer, &settings)
+		if err != nil {
+			log.WithError(err).Error("error drawing the parameter panel")
+		}
+		if growth.Active {
+			err = drawNotice(renderer, &settings, 1, fmt.Sprintf("iterations %d", settings.MaxIterations))
+			if err != nil {
+				log.WithError(err).Error("error drawing the animation notice")
+			}
+		}
+		if m := settings.Magnification(); math.Abs(m-1) > 1e-9 {
+			err = drawNotice(renderer, &settings, 2, "zoom "+formatMagnification(m))
+			if err != nil {
+				log.WithError(err).Error("error drawing the magnification")
+			}
+		}
+		if area.Active {
+			estimate, stderr := area.Estimate(&settings)
+			err = drawNotice(renderer, &settings, 3, fmt.Sprintf("area %.5f +- %.5f (%d samples)", estimate, stderr, area.samples))
+			if err != nil {
+				log.WithError(err).Error("error drawing the area estimate")
+			}
+		}
+		if frozen {
+			err = drawNotice(renderer, &settings, 5, "frozen")
+			if err != nil {
+				log.WithError(err).Error("error drawing the frozen notice")
+			}
+		}
+		if probe.Label != "" {
+			err = drawNotice(renderer, &settings, 4, probe.Label)
+			if err != nil {
+				log.WithError(err).Error("error drawing the probed period")
+			}
+		}
+		if settings.Fractal == FractalJulia {
+			err = drawNotice(renderer, &settings, 6, juliaNotice(&settings))
+			if err != nil {
+				log.WithError(err).Error("error drawing the Julia constant")
+			}
+		}
+		if time.Now().Before(savedUntil) {
+			err = drawNotice(renderer, &settings, 7, savedNotice)
+			if err != nil {
+				log.WithError(err).Error("error drawing the save notice")
+			}
+		}
+		if precisionExhausted {
+			err = drawNotice(renderer, &settings, 0, "precision limit reached")
+			if err != nil {
+				log.WithError(err).Error("error drawing the precision notice")
+			}
+		}
 
-		sdl.Delay(500)
+		// animations get frames often enough to move smoothly; otherwise the
+		// loop only needs to keep up with input
+		delay := idleFrameDelay
+		if animating {
+			delay = animationFrameDelay
+		}
+		sdl.Delay(uint32(delay / time.Millisecond))
 		renderer.Present()
 	}
+	// let saves still encoding finish before exiting
+	for pendingSaves > 0 {
+		reportSave(<-saves)
+	}
 }
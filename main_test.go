@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// testSettings is the startup view at width x height, with the defaults
+// the flags would give it.
+func testSettings(width, height float64) Settings {
+	s := Settings{
+		Width:          width,
+		Height:         height,
+		MaxIterations:  200,
+		VarianceWindow: 40,
+
+		PeriodDetection: true,
+		IterationStep:   25,
+		IterationLimit:  100000,
+
+		AASamples:     3,
+		EdgeThreshold: 1,
+		DetailRegion:  96,
+		DetailSamples: 8,
+
+		ColorDensity: 1,
+		ZoomFactor:   1.25,
+		PixelAspect:  1,
+		SmoothBlend:  1,
+
+		ContourSpacing: 10,
+		LightAzimuth:   135,
+		LightElevation: 45,
+		MaxSpan:        6,
+		MaxPasses:      1,
+
+		Julia: defaultJulia,
+	}
+	s.ApplyView(homeView)
+	s.InitialSpan = s.Max - s.Min
+	return s
+}
+
+// startImage is a MandelbrotImage for settings with its writer running.
+func startImage(settings *Settings) *MandelbrotImage {
+	mi := NewMandelbrotImage(settings.Width, settings.Height, settings)
+	go imageWriter(mi, mi.Jobs)
+	mi.Init()
+	return mi
+}
+
+// finish waits for the background work of the last render to run to the
+// end and be drawn, where Close would cut it short, and stops the writer
+// and the render pool.
+func finish(mi *MandelbrotImage) {
+	mi.senders.Wait()
+	mi.sizePool(0)
+	close(mi.Jobs)
+	<-mi.written
+}
+
+// samePixels reports the first pixel of got that differs from want.
+func samePixels(t *testing.T, got, want []byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("pixel %d differs: got %v, want %v", i/4, got[i/4*4:i/4*4+4], want[i/4*4:i/4*4+4])
+		}
+	}
+}
+
+// BenchmarkForceRender renders the startup view at the default size, on
+// one worker and on one per CPU.
+func BenchmarkForceRender(b *testing.B) {
+	counts := []int{1}
+	if runtime.NumCPU() > 1 {
+		counts = append(counts, runtime.NumCPU())
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			settings := testSettings(800, 800)
+			mi := startImage(&settings)
+			defer mi.Close()
+			mi.Workers = workers
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mi.ForceRender()
+			}
+		})
+	}
+}
+
+// TestInterruptedRender checks that Interrupt cuts a blocking render short
+// at its first check, and that the next render without it draws the whole
+// frame.
+func TestInterruptedRender(t *testing.T) {
+	settings := testSettings(64, 64)
+	mi := startImage(&settings)
+	polls := 0
+	mi.Interrupt = func() bool {
+		polls++
+		return true
+	}
+	mi.InterruptEvery = 4
+	mi.ForceRender()
+	if !mi.Interrupted() || polls != 1 {
+		t.Fatalf("interrupted %v after %d polls; want the first poll to interrupt", mi.Interrupted(), polls)
+	}
+	cut := mi.Snapshot()
+
+	mi.Interrupt = nil
+	mi.ForceRender()
+	if mi.Interrupted() {
+		t.Error("a render without Interrupt was interrupted")
+	}
+	finish(mi)
+
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+
+	// the three columns before the first poll are drawn, and the rest
+	// left as they were
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			drawn := cut.RGBAAt(x, y) == want.Image.RGBAAt(x, y)
+			if x < 3 && !drawn {
+				t.Fatalf("the interrupted render didn't draw (%d, %d)", x, y)
+			}
+			if x >= 3 && drawn && want.Image.RGBAAt(x, y) != (color.RGBA{A: 255}) {
+				t.Fatalf("the interrupted render drew (%d, %d), past the first poll", x, y)
+			}
+		}
+	}
+}
+
+// TestForceRenderWritesEveryPixel renders over a background no pixel of
+// the frame has, on one worker and several, and checks that every pixel
+// came out as in a headless render.
+func TestForceRenderWritesEveryPixel(t *testing.T) {
+	settings := testSettings(48, 32)
+	settings.BackgroundColor = color.RGBA{R: 255, B: 255, A: 255}
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 3} {
+		mi := startImage(&settings)
+		mi.Workers = workers
+		mi.ForceRender()
+		finish(mi)
+
+		got := mi.Snapshot()
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 48; x++ {
+				if got.RGBAAt(x, y) == settings.BackgroundColor {
+					t.Fatalf("%d workers: (%d, %d) was never written", workers, x, y)
+				}
+			}
+		}
+		samePixels(t, got.Pix, want.Image.Pix)
+	}
+}
+
+// TestWorkerCountInvariance renders the same views on 1, 2 and 4 workers,
+// checking that the pixels and iteration counts come out byte-identical
+// whatever the count: a view off the real axis, one mirrored about it, a
+// blocky preview, and a frame with its edges refined.
+func TestWorkerCountInvariance(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(s *Settings)
+		render    func(mi *MandelbrotImage)
+		symmetric bool
+	}{
+		{name: "asymmetric", setup: func(s *Settings) { s.CenterOn(-0.75, 0.2, 1.5) }},
+		{name: "mirrored", setup: func(s *Settings) {}, symmetric: true},
+		// blocks turn the mirroring off even on a symmetric view
+		{name: "blocks", setup: func(s *Settings) {}, render: func(mi *MandelbrotImage) { mi.ForcePreview(1, true, 4) }, symmetric: true},
+		{name: "refined edges", setup: func(s *Settings) { s.AdaptiveAA = true; s.CenterOn(-0.75, 0.2, 1.5) }},
+	}
+	for _, tt := range tests {
+		var pixels []byte
+		var iterations []int64
+		for _, workers := range []int{1, 2, 4} {
+			settings := testSettings(61, 40)
+			tt.setup(&settings)
+			if symmetricView(&settings) != tt.symmetric {
+				t.Fatalf("%s: symmetricView is %v", tt.name, !tt.symmetric)
+			}
+			mi := startImage(&settings)
+			mi.Workers = workers
+			if tt.render != nil {
+				tt.render(mi)
+			} else {
+				mi.ForceRender()
+			}
+			finish(mi)
+
+			if pixels == nil {
+				pixels, iterations = mi.Pixels, mi.Iterations
+				continue
+			}
+			if !bytes.Equal(mi.Pixels, pixels) {
+				t.Errorf("%s: the pixels on %d workers differ from those on one", tt.name, workers)
+			}
+			if !reflect.DeepEqual(mi.Iterations, iterations) {
+				t.Errorf("%s: the iteration counts on %d workers differ from those on one", tt.name, workers)
+			}
+		}
+	}
+}
+
+// TestImageWriterDrain sends every pixel of a small image exactly once
+// through Jobs from several goroutines, as the background work does, and
+// checks that once Close returns each pixel holds the point sent for it.
+func TestImageWriterDrain(t *testing.T) {
+	settings := testSettings(32, 24)
+	mi := startImage(&settings)
+	generation := mi.generation
+
+	const senders = 4
+	for s := 0; s < senders; s++ {
+		mi.senders.Add(1)
+		go func(s int) {
+			defer mi.senders.Done()
+			for idx := s; idx < 32*24; idx += senders {
+				mi.Jobs <- Point{
+					X:          float64(idx % 32),
+					Y:          float64(idx / 32),
+					Red:        uint8(idx),
+					Green:      uint8(idx >> 8),
+					Iterations: int64(idx),
+					Generation: generation,
+				}
+			}
+		}(s)
+	}
+	// everything is sent before Close, which would stop the senders
+	mi.senders.Wait()
+	mi.Close()
+
+	select {
+	case <-mi.written:
+	default:
+		t.Fatal("Close returned before imageWriter stopped")
+	}
+	for idx, iters := range mi.Iterations {
+		if iters != int64(idx) || mi.Pixels[idx*4] != uint8(idx) || mi.Pixels[idx*4+1] != uint8(idx>>8) {
+			t.Fatalf("pixel %d holds the point for %d", idx, iters)
+		}
+	}
+}
+
+// TestCloseDuringRefinement closes the image while edge refinement is still
+// sending, which must neither panic on the closed Jobs channel nor hang.
+func TestCloseDuringRefinement(t *testing.T) {
+	settings := testSettings(128, 128)
+	settings.AdaptiveAA = true
+	settings.AASamples = 8
+	mi := startImage(&settings)
+	mi.ForceRender()
+	mi.Close()
+
+	select {
+	case <-mi.written:
+	default:
+		t.Fatal("Close returned before imageWriter stopped")
+	}
+}
+
+// TestCloseLeavesNoGoroutines starts an image as the app does, renders
+// with background refinement and passes, and checks that Close leaves no
+// goroutine behind.
+func TestCloseLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for _, passes := range []int64{1, 3} {
+		settings := testSettings(96, 96)
+		settings.AdaptiveAA = true
+		settings.MaxPasses = passes
+		mi := startImage(&settings)
+		mi.ForceRender()
+		if err := mi.RefineRegion(10, 10, 40, 40); err != nil {
+			t.Fatal(err)
+		}
+		mi.Close()
+	}
+
+	// goroutines that are done may take a moment to be gone
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines before the image and %d after Close", before, after)
+	}
+}
+
+// TestRenderPool checks that the render pool is started with the image
+// and kept from frame to frame rather than started again for each, that
+// it follows Workers, and that the frames it renders are whole.
+func TestRenderPool(t *testing.T) {
+	settings := testSettings(64, 48)
+	mi := startImage(&settings)
+	if mi.poolSize != runtime.NumCPU() {
+		t.Errorf("the image started %d workers; want one per CPU", mi.poolSize)
+	}
+
+	// each frame's background work is waited for, so only the pool and
+	// the writer are left running between frames
+	mi.ForceRender()
+	mi.senders.Wait()
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		mi.ForceRender()
+		mi.senders.Wait()
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if now := runtime.NumGoroutine(); now > goroutines {
+		t.Errorf("%d goroutines after one frame and %d after five more", goroutines, now)
+	}
+
+	for _, workers := range []int{3, 1} {
+		mi.Workers = workers
+		mi.ForceRender()
+		if mi.poolSize != workers {
+			t.Errorf("with %d workers the pool has %d", workers, mi.poolSize)
+		}
+	}
+	finish(mi)
+	if mi.poolSize != 0 {
+		t.Errorf("%d workers are left after finishing", mi.poolSize)
+	}
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+}
+
+// TestResizeReusesBuffers shrinks an image and grows it back, which should
+// keep the buffers it started with, and then checks that it renders at the
+// new size.
+func TestResizeReusesBuffers(t *testing.T) {
+	settings := testSettings(64, 48)
+	mi := NewMandelbrotImage(settings.Width, settings.Height, &settings)
+	pixels, iterations, smooth := &mi.Pixels[0], &mi.Iterations[0], &mi.Smooth[0]
+
+	for _, size := range [][2]float64{{32, 24}, {48, 64}, {64, 48}} {
+		mi.Resize(size[0], size[1])
+		n := int(size[0] * size[1])
+		if len(mi.Pixels) != n*4 || len(mi.Iterations) != n || len(mi.Smooth) != n {
+			t.Fatalf("%vx%v: got buffers of %d, %d and %d", size[0], size[1],
+				len(mi.Pixels), len(mi.Iterations), len(mi.Smooth))
+		}
+		if &mi.Pixels[0] != pixels || &mi.Iterations[0] != iterations || &mi.Smooth[0] != smooth {
+			t.Fatalf("%vx%v: the buffers were reallocated", size[0], size[1])
+		}
+	}
+
+	mi.Resize(128, 96)
+	if len(mi.Pixels) != 128*96*4 || &mi.Pixels[0] == pixels {
+		t.Fatalf("growing past the capacity gave %d bytes in the old buffer", len(mi.Pixels))
+	}
+
+	settings.Width, settings.Height = 128, 96
+	go imageWriter(mi, mi.Jobs)
+	mi.Init()
+	mi.ForceRender()
+	finish(mi)
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+}
+
+// TestCloneIsIndependent changes a clone in every way a field can share
+// memory with the original, checking that the original doesn't see it,
+// and that no new field that can has been left out of Clone.
+func TestCloneIsIndependent(t *testing.T) {
+	settings := testSettings(64, 64)
+	settings.ColorOverrides = map[int]color.RGBA{3: {R: 255, A: 255}}
+
+	clone := settings.Clone()
+	if !reflect.DeepEqual(clone, settings) {
+		t.Fatal("the clone differs from the original")
+	}
+	clone.ColorOverrides[3] = color.RGBA{G: 255, A: 255}
+	clone.ColorOverrides[4] = color.RGBA{B: 255, A: 255}
+	clone.MaxIterations++
+	clone.Julia.X++
+	if len(settings.ColorOverrides) != 1 || settings.ColorOverrides[3] != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("changing the clone's overrides changed the original's to %v", settings.ColorOverrides)
+	}
+	if settings.MaxIterations != 200 || settings.Julia != defaultJulia {
+		t.Error("changing the clone changed the original")
+	}
+
+	// Clone deep-copies ColorOverrides; any other field that shares
+	// memory needs the same
+	copied := map[string]bool{"ColorOverrides": true}
+	typ := reflect.TypeOf(Settings{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Chan, reflect.Func, reflect.Interface:
+			if !copied[field.Name] {
+				t.Errorf("Clone shares Settings.%s, a %v, with the original", field.Name, field.Type)
+			}
+		}
+	}
+}
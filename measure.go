@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// measureTool places two markers on the image with the mouse and reports
+// the distance between them in pixels and in complex-plane units.
+type measureTool struct {
+	Active  bool
+	markers []Point
+}
+
+func (m *measureTool) Toggle() {
+	m.Active = !m.Active
+	m.Clear()
+}
+
+func (m *measureTool) Clear() {
+	m.markers = m.markers[:0]
+}
+
+// Place adds a marker at the pixel (px, py); a third click starts a new
+// measurement.
+func (m *measureTool) Place(px, py float64, settings *Settings) {
+	if len(m.markers) == 2 {
+		m.Clear()
+	}
+	m.markers = append(m.markers, Point{X: px, Y: py})
+	if len(m.markers) < 2 {
+		return
+	}
+
+	a, b := m.markers[0], m.markers[1]
+	are, aim := settings.PixelToComplex(a.X, a.Y)
+	bre, bim := settings.PixelToComplex(b.X, b.Y)
+
+	log.WithFields(log.Fields{
+		"from":    []float64{are, aim},
+		"to":      []float64{bre, bim},
+		"complex": math.Hypot(bre-are, bim-aim),
+		"pixels":  math.Hypot(b.X-a.X, b.Y-a.Y),
+	}).Info("measured distance")
+}
+
+// Draw overlays the markers and the line between them.
+func (m *measureTool) Draw(renderer *sdl.Renderer) error {
+	if len(m.markers) == 0 {
+		return nil
+	}
+
+	err := renderer.SetDrawColor(255, 255, 255, 255)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	for _, pt := range m.markers {
+		err = renderer.DrawRect(&sdl.Rect{X: int32(pt.X) - 2, Y: int32(pt.Y) - 2, W: 5, H: 5})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(m.markers) == 2 {
+		a, b := m.markers[0], m.markers[1]
+		return renderer.DrawLine(int32(a.X), int32(a.Y), int32(b.X), int32(b.Y))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// viewState is the part of Settings whose changes are traced, to
+// reconstruct how a reported view was reached.
+type viewState struct {
+	CenterX       float64
+	CenterY       float64
+	Min           float64
+	Max           float64
+	MaxIterations int64
+	Fractal       FractalMode
+	Julia         Point
+}
+
+func viewStateOf(settings *Settings) viewState {
+	return viewState{
+		CenterX:       settings.Center.X,
+		CenterY:       settings.Center.Y,
+		Min:           settings.Min,
+		Max:           settings.Max,
+		MaxIterations: settings.MaxIterations,
+		Fractal:       settings.Fractal,
+		Julia:         settings.Julia,
+	}
+}
+
+// eventAction names the input behind event for the trace.
+func eventAction(event sdl.Event) string {
+	switch t := event.(type) {
+	case *sdl.KeyboardEvent:
+		return "key " + sdl.GetKeyName(t.Keysym.Sym)
+	case *sdl.MouseButtonEvent:
+		return fmt.Sprintf("mouse button %d at %d,%d", t.Button, t.X, t.Y)
+	case *sdl.MouseWheelEvent:
+		return fmt.Sprintf("mouse wheel %d,%d", t.X, t.Y)
+	}
+	return fmt.Sprintf("event %T", event)
+}
+
+// traceViewChange logs the view at debug level if action changed it since
+// before.
+func traceViewChange(before viewState, settings *Settings, action string) {
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		return
+	}
+	after := viewStateOf(settings)
+	if after == before {
+		return
+	}
+
+	fields := log.Fields{
+		"action":     action,
+		"center_x":   after.CenterX,
+		"center_y":   after.CenterY,
+		"min":        after.Min,
+		"max":        after.Max,
+		"iterations": after.MaxIterations,
+	}
+	if after.Fractal == FractalJulia {
+		fields["julia"] = formatJulia(after.Julia)
+	}
+	log.WithFields(fields).Debug("view changed")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// normalMap encodes the surface normals of the iteration height field, the
+// same ones relief shading lights, as a tangent-space normal map: each
+// component in [-1, 1] is stored as (n+1)/2 scaled to 0-255, with red the
+// x axis to the right, green the y axis and blue z out of the image. Green
+// points up the image, the OpenGL convention, unless directX is set, when
+// it points down. With deep set the channels are 16 bits.
+func normalMap(iters []int64, width, height int, directX, deep bool) image.Image {
+	var img *image.RGBA
+	var deepImg *image.NRGBA64
+	if deep {
+		deepImg = image.NewNRGBA64(image.Rect(0, 0, width, height))
+	} else {
+		img = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	encode := func(n float64) uint8 {
+		return uint8(math.Round((n + 1) / 2 * 255))
+	}
+	encode16 := func(n float64) uint16 {
+		return uint16(math.Round((n + 1) / 2 * 65535))
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			nx, ny, nz := heightNormal(iters, width, height, x, y)
+			// heightNormal's y runs down the image
+			if !directX {
+				ny = -ny
+			}
+			if deep {
+				deepImg.SetNRGBA64(x, y, color.NRGBA64{R: encode16(nx), G: encode16(ny), B: encode16(nz), A: 0xffff})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{R: encode(nx), G: encode(ny), B: encode(nz), A: 255})
+			}
+		}
+	}
+	if deep {
+		return deepImg
+	}
+	return img
+}
+
+func runNormalMap(path string, directX bool, settings *Settings, export exportOptions, stats io.Writer) error {
+	r, err := renderImage(settings, false)
+	if err != nil {
+		return err
+	}
+	if err := writeStats(stats, r.Stats); err != nil {
+		return err
+	}
+	return writeImage(path, normalMap(r.Iterations, int(settings.Width), int(settings.Height), directX, export.BitDepth == 16), export)
+}
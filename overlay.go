@@ -0,0 +1,30 @@
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+const noticeScale = 2
+
+// drawNotice draws a short status message on a dark backdrop along the
+// bottom-left of the image; slot 0 is the bottom line and higher slots stack
+// upwards.
+func drawNotice(renderer *sdl.Renderer, settings *Settings, slot int, text string) error {
+	height := int32(glyphHeight*noticeScale + 2*panelPadding)
+	x := int32(panelPadding)
+	y := int32(settings.Height) - int32(panelPadding) - int32(slot+1)*(height+panelPadding/2)
+
+	err := renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	renderer.SetDrawColor(0, 0, 0, 180)
+	err = renderer.FillRect(&sdl.Rect{X: x, Y: y, W: textWidth(text, noticeScale) + 2*panelPadding, H: height})
+	if err != nil {
+		return err
+	}
+
+	renderer.SetDrawColor(255, 200, 0, 255)
+	return drawText(renderer, x+panelPadding, y+panelPadding, noticeScale, text)
+}
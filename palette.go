@@ -0,0 +1,238 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Palette maps a position t in [0, 1] along the iteration range, after
+// tone mapping, to red, green and blue intensities on the 0-255 scale as
+// they appear on screen. Interior pixels are black whatever the palette.
+type Palette interface {
+	Color(t float64) (red, green, blue float64)
+}
+
+// paletteRegistration names a palette for Settings.Palette.
+type paletteRegistration struct {
+	Name    string
+	Palette Palette
+}
+
+// palettes lists the available palettes; the first entry is the default.
+var palettes = []paletteRegistration{
+	{Name: "classic", Palette: classicPalette{}},
+	{Name: "grayscale", Palette: grayscalePalette{}},
+	{Name: "gold", Palette: goldPalette},
+}
+
+// classicPalette is the original coloring: red rises fastest and blue
+// slowest, for warm browns fading into the set.
+type classicPalette struct{}
+
+func (classicPalette) Color(t float64) (float64, float64, float64) {
+	col := t * 255
+	return mapToRange(math.Sqrt(col), 0, math.Sqrt(255), 0, 255),
+		mapToRange(col/2, 0, 255/2, 0, 255),
+		mapToRange(col*col, 0, 255*255, 0, 255)
+}
+
+type grayscalePalette struct{}
+
+func (grayscalePalette) Color(t float64) (float64, float64, float64) {
+	return t * 255, t * 255, t * 255
+}
+
+// gradientStop is a color a gradient passes through at position At.
+type gradientStop struct {
+	At      float64
+	R, G, B float64
+}
+
+// Interpolation is how a gradient blends from one stop to the next.
+// InterpolationDefault leaves each gradient on its own mode.
+type Interpolation int
+
+const (
+	InterpolationDefault Interpolation = iota
+	InterpolationLinear
+	InterpolationSmoothstep
+	InterpolationCatmullRom
+	interpolationCount
+)
+
+func (i Interpolation) String() string {
+	switch i {
+	case InterpolationLinear:
+		return "linear"
+	case InterpolationSmoothstep:
+		return "smoothstep"
+	case InterpolationCatmullRom:
+		return "catmull-rom"
+	}
+	return "default"
+}
+
+func parseInterpolation(name string) (Interpolation, error) {
+	var i Interpolation
+	for i = 0; i < interpolationCount; i++ {
+		if i.String() == name {
+			return i, nil
+		}
+	}
+	return InterpolationDefault, errors.Errorf("unknown interpolation %q; use default, linear, smoothstep or catmull-rom", name)
+}
+
+// gradient blends between its stops, which are in order of At from 0 to
+// 1, by Mode, which is linear unless set. Every mode passes through the
+// stops exactly; smoothstep eases in and out of each one, and Catmull-Rom
+// curves through them with no corners.
+type gradient struct {
+	Stops []gradientStop
+	Mode  Interpolation
+}
+
+func (g gradient) Color(t float64) (float64, float64, float64) {
+	stops := g.Stops
+	if t <= stops[0].At {
+		return stops[0].R, stops[0].G, stops[0].B
+	}
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].At {
+			continue
+		}
+		a, b := stops[i-1], stops[i]
+		f := (t - a.At) / (b.At - a.At)
+		switch g.Mode {
+		case InterpolationSmoothstep:
+			f = f * f * (3 - 2*f)
+		case InterpolationCatmullRom:
+			return g.catmullRom(i, f)
+		}
+		return a.R + (b.R-a.R)*f, a.G + (b.G-a.G)*f, a.B + (b.B-a.B)*f
+	}
+	last := stops[len(stops)-1]
+	return last.R, last.G, last.B
+}
+
+// catmullRom is the Hermite curve from stop i-1 to stop i at fraction f,
+// with the tangent at each stop the slope between its neighbors. Curves
+// can overshoot, so channels are clamped to 0-255.
+func (g gradient) catmullRom(i int, f float64) (float64, float64, float64) {
+	stops := g.Stops
+	channel := func(c func(gradientStop) float64) float64 {
+		tangent := func(j int) float64 {
+			lo, hi := j-1, j+1
+			if lo < 0 {
+				lo = 0
+			}
+			if hi >= len(stops) {
+				hi = len(stops) - 1
+			}
+			// a hard edge has stops at the same position
+			if stops[hi].At == stops[lo].At {
+				return 0
+			}
+			return (c(stops[hi]) - c(stops[lo])) / (stops[hi].At - stops[lo].At)
+		}
+		h := stops[i].At - stops[i-1].At
+		f2, f3 := f*f, f*f*f
+		v := (2*f3-3*f2+1)*c(stops[i-1]) + (f3-2*f2+f)*h*tangent(i-1) +
+			(-2*f3+3*f2)*c(stops[i]) + (f3-f2)*h*tangent(i)
+		return math.Max(0, math.Min(255, v))
+	}
+	return channel(func(s gradientStop) float64 { return s.R }),
+		channel(func(s gradientStop) float64 { return s.G }),
+		channel(func(s gradientStop) float64 { return s.B })
+}
+
+// goldPalette runs from deep blue through white to gold.
+var goldPalette = gradient{Stops: []gradientStop{
+	{At: 0, R: 0, G: 7, B: 100},
+	{At: 0.35, R: 32, G: 107, B: 203},
+	{At: 0.65, R: 237, G: 255, B: 255},
+	{At: 1, R: 255, G: 170, B: 0},
+}}
+
+func paletteName(settings *Settings) string {
+	if settings.Palette == "" {
+		return palettes[0].Name
+	}
+	return settings.Palette
+}
+
+// paletteFor is the palette named by settings.Palette, or the default if
+// there is no such palette, with gradients on settings.Interpolation.
+func paletteFor(settings *Settings) Palette {
+	p := palettes[0].Palette
+	for _, r := range palettes {
+		if r.Name == paletteName(settings) {
+			p = r.Palette
+		}
+	}
+	if f, ok := p.(*paletteFile); ok {
+		p = f.Palette()
+	}
+	if g, ok := p.(gradient); ok && settings.Interpolation != InterpolationDefault {
+		g.Mode = settings.Interpolation
+		return g
+	}
+	return p
+}
+
+// parsePalette checks that name is a palette; empty selects the default.
+func parsePalette(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	names := make([]string, len(palettes))
+	for i, p := range palettes {
+		if p.Name == name {
+			return name, nil
+		}
+		names[i] = p.Name
+	}
+	return "", errors.Errorf("unknown palette %q; use %s", name, strings.Join(names, ", "))
+}
+
+// cyclePalette steps settings.Palette forwards or backwards through the
+// palettes.
+func cyclePalette(settings *Settings, dir int) {
+	current := 0
+	for i, p := range palettes {
+		if p.Name == paletteName(settings) {
+			current = i
+		}
+	}
+	settings.Palette = palettes[(current+dir+len(palettes))%len(palettes)].Name
+}
+
+// paletteSwap remembers the two most recently used palettes, for the key
+// that flips between them.
+type paletteSwap struct {
+	current, previous string
+}
+
+// Track notes the palette settings is on. It is called after anything that
+// might have changed it.
+func (p *paletteSwap) Track(settings *Settings) {
+	if name := paletteName(settings); name != p.current {
+		if p.current != "" {
+			p.previous = p.current
+		}
+		p.current = name
+	}
+}
+
+// Swap puts settings back on the palette used before the current one,
+// reporting false if no other palette has been used yet.
+func (p *paletteSwap) Swap(settings *Settings) bool {
+	p.Track(settings)
+	if p.previous == "" {
+		return false
+	}
+	settings.Palette = p.previous
+	p.Track(settings)
+	return true
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPaletteColors checks each palette at the ends and middle of its
+// range, and that it stays within its range in between.
+func TestPaletteColors(t *testing.T) {
+	type rgb [3]float64
+	tests := []struct {
+		name            string
+		start, mid, end rgb
+		// top is the highest any channel goes
+		top float64
+	}{
+		// the original coloring scales green by 255/127, the integer
+		// 255/2, so it runs a little past 255 and quantizing clamps it
+		{name: "classic", start: rgb{0, 0, 0}, mid: rgb{math.Sqrt(127.5) / math.Sqrt(255) * 255, 63.75 * 255 / 127, 63.75}, end: rgb{255, 127.5 * 255 / 127, 255}, top: 127.5 * 255 / 127},
+		{name: "grayscale", start: rgb{0, 0, 0}, mid: rgb{127.5, 127.5, 127.5}, end: rgb{255, 255, 255}, top: 255},
+		{name: "gold", start: rgb{0, 7, 100}, mid: rgb{134.5, 181, 229}, end: rgb{255, 170, 0}, top: 255},
+	}
+	if len(tests) != len(palettes) {
+		t.Errorf("%d palettes are registered and %d tested", len(palettes), len(tests))
+	}
+	for _, tt := range tests {
+		p := paletteFor(&Settings{Palette: tt.name})
+		for _, c := range []struct {
+			at   float64
+			want rgb
+		}{{0, tt.start}, {0.5, tt.mid}, {1, tt.end}} {
+			r, g, b := p.Color(c.at)
+			got := rgb{r, g, b}
+			for i := range got {
+				if math.Abs(got[i]-c.want[i]) > 1e-9 {
+					t.Errorf("%s at %v is %v; want %v", tt.name, c.at, got, c.want)
+					break
+				}
+			}
+		}
+		for i := 0; i <= 1000; i++ {
+			r, g, b := p.Color(float64(i) / 1000)
+			for _, v := range []float64{r, g, b} {
+				if v < 0 || v > tt.top+1e-9 || math.IsNaN(v) {
+					t.Fatalf("%s at %v is %v, %v, %v", tt.name, float64(i)/1000, r, g, b)
+				}
+			}
+		}
+	}
+}
+
+// TestPaletteSelection checks parsing and cycling through the palettes,
+// and that an unknown name falls back to the default.
+func TestPaletteSelection(t *testing.T) {
+	for _, p := range palettes {
+		if name, err := parsePalette(p.Name); err != nil || name != p.Name {
+			t.Errorf("%s parsed as %q, %v", p.Name, name, err)
+		}
+	}
+	if name, err := parsePalette(""); err != nil || name != "" {
+		t.Errorf("an empty palette parsed as %q, %v", name, err)
+	}
+	if _, err := parsePalette("plaid"); err == nil {
+		t.Error("an unknown palette parsed")
+	}
+	if paletteFor(&Settings{Palette: "plaid"}) != palettes[0].Palette {
+		t.Error("an unknown palette didn't fall back to the default")
+	}
+
+	var settings Settings
+	for i := 1; i <= len(palettes); i++ {
+		cyclePalette(&settings, 1)
+		if want := palettes[i%len(palettes)].Name; settings.Palette != want {
+			t.Errorf("stepping forwards %d times selected %q; want %q", i, settings.Palette, want)
+		}
+	}
+	cyclePalette(&settings, -1)
+	if want := palettes[len(palettes)-1].Name; settings.Palette != want {
+		t.Errorf("stepping back from the first selected %q; want %q", settings.Palette, want)
+	}
+}
+
+// TestPaletteSwap flips between the two most recently used palettes, and
+// checks that recoloring the cached frame after a swap gives what a fresh
+// render on that palette does.
+func TestPaletteSwap(t *testing.T) {
+	var swap paletteSwap
+	settings := testSettings(48, 32)
+	swap.Track(&settings)
+	if swap.Swap(&settings) || settings.Palette != "" {
+		t.Fatalf("swapping before any other palette was used selected %q", settings.Palette)
+	}
+
+	settings.Palette = "grayscale"
+	swap.Track(&settings)
+	settings.Palette = "gold"
+	swap.Track(&settings)
+	for _, want := range []string{"grayscale", "gold", "grayscale"} {
+		if !swap.Swap(&settings) || settings.Palette != want {
+			t.Errorf("swapped to %q; want %q", settings.Palette, want)
+		}
+	}
+
+	mi := startImage(&settings)
+	mi.ForceRender()
+	finish(mi)
+	swap.Swap(&settings)
+	mi.Recolor()
+
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, mi.Snapshot().Pix, want.Image.Pix)
+}
+
+// TestGradientInterpolation checks that every interpolation mode passes
+// through the stops with no jump either side of them, stays in range, and
+// blends as its formula says between them.
+func TestGradientInterpolation(t *testing.T) {
+	for i := InterpolationDefault; i < interpolationCount; i++ {
+		if got, err := parseInterpolation(i.String()); err != nil || got != i {
+			t.Errorf("%v parsed as %v, %v", i, got, err)
+		}
+	}
+	if _, err := parseInterpolation("cubic"); err == nil {
+		t.Error("an unknown interpolation parsed")
+	}
+
+	const eps = 1e-9
+	near := func(a, b [3]float64, tolerance float64) bool {
+		for c := range a {
+			if math.Abs(a[c]-b[c]) > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	for mode := InterpolationLinear; mode < interpolationCount; mode++ {
+		g := goldPalette
+		g.Mode = mode
+		at := func(t float64) [3]float64 {
+			r, gr, b := g.Color(t)
+			return [3]float64{r, gr, b}
+		}
+		for _, stop := range g.Stops {
+			want := [3]float64{stop.R, stop.G, stop.B}
+			if got := at(stop.At); !near(got, want, 1e-9) {
+				t.Errorf("%v at the stop %v is %v; want %v", mode, stop.At, got, want)
+			}
+			if below, above := at(stop.At-eps), at(stop.At+eps); !near(below, want, 1e-4) || !near(above, want, 1e-4) {
+				t.Errorf("%v jumps at the stop %v: %v, %v either side of %v", mode, stop.At, below, above, want)
+			}
+		}
+		for i := 0; i <= 1000; i++ {
+			for _, v := range at(float64(i) / 1000) {
+				if v < 0 || v > 255 || math.IsNaN(v) {
+					t.Fatalf("%v at %v is %v", mode, float64(i)/1000, at(float64(i)/1000))
+				}
+			}
+		}
+	}
+
+	two := gradient{Stops: []gradientStop{{At: 0}, {At: 1, R: 200, G: 100, B: 40}}}
+	for _, tt := range []struct {
+		mode Interpolation
+		want float64
+	}{
+		{InterpolationLinear, 0.25},
+		{InterpolationSmoothstep, 0.25 * 0.25 * (3 - 2*0.25)},
+		// with only two stops the tangents are the line between them
+		{InterpolationCatmullRom, 0.25},
+	} {
+		two.Mode = tt.mode
+		if r, g, b := two.Color(0.25); !near([3]float64{r, g, b}, [3]float64{200 * tt.want, 100 * tt.want, 40 * tt.want}, 1e-9) {
+			t.Errorf("%v a quarter of the way is %v, %v, %v; want %v of the way", tt.mode, r, g, b, tt.want)
+		}
+	}
+}
+
+// TestInterpolationSetting checks that settings.Interpolation reaches
+// gradient palettes and leaves the gold gradient alone by default.
+func TestInterpolationSetting(t *testing.T) {
+	settings := Settings{Palette: "gold"}
+	if g := paletteFor(&settings).(gradient); g.Mode != InterpolationDefault {
+		t.Errorf("the default setting put gold on %v", g.Mode)
+	}
+	settings.Interpolation = InterpolationCatmullRom
+	if g := paletteFor(&settings).(gradient); g.Mode != InterpolationCatmullRom {
+		t.Errorf("gold is on %v; want catmull-rom", g.Mode)
+	}
+	if goldPalette.Mode != InterpolationDefault {
+		t.Error("selecting an interpolation changed the registered gradient")
+	}
+	if colorKeyFor(&settings) == colorKeyFor(&Settings{Palette: "gold"}) {
+		t.Error("the color table doesn't depend on the interpolation")
+	}
+}
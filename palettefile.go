@@ -0,0 +1,294 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// paletteRevision counts palette file reloads, so color tables built from
+// an earlier version of a palette are rebuilt.
+var paletteRevision int64
+
+// LoadPalette reads the gradient in a GIMP .ggr file, or in a list of
+// CSS-style hex color stops such as "#000764 0%, #206bcb 35%, #ffaa00".
+func LoadPalette(path string) (Palette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read the palette")
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var g gradient
+	if filepath.Ext(path) == ".ggr" || strings.HasPrefix(text, ggrHeader) {
+		g, err = parseGGR(text)
+	} else {
+		g, err = parseHexStops(text)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", path)
+	}
+	return g, nil
+}
+
+const ggrHeader = "GIMP Gradient"
+
+// GIMP's segment blending functions, in the order .ggr files number them.
+const (
+	ggrLinear = iota
+	ggrCurved
+	ggrSine
+	ggrSphereIncreasing
+	ggrSphereDecreasing
+	ggrStep
+)
+
+// ggrSamples is how many stops a curved segment is approximated with.
+const ggrSamples = 16
+
+// ggrSegment is one line of a .ggr file: a blend from From at Left to To at
+// Right, halfway there at Mid.
+type ggrSegment struct {
+	Left, Mid, Right float64
+	From, To         [3]float64
+	Blend            int
+}
+
+// factor is how far the segment has blended from From to To at pos, a
+// fraction of the way across it, as GIMP works it out.
+func (s ggrSegment) factor(pos float64) float64 {
+	const epsilon = 1e-10
+	mid := (s.Mid - s.Left) / (s.Right - s.Left)
+	linear := func() float64 {
+		if pos <= mid {
+			if mid < epsilon {
+				return 0
+			}
+			return 0.5 * pos / mid
+		}
+		if 1-mid < epsilon {
+			return 1
+		}
+		return 0.5 + 0.5*(pos-mid)/(1-mid)
+	}
+	switch s.Blend {
+	case ggrCurved:
+		return math.Pow(pos, math.Log(0.5)/math.Log(math.Max(mid, epsilon)))
+	case ggrSine:
+		return (math.Sin(-math.Pi/2+math.Pi*linear()) + 1) / 2
+	case ggrSphereIncreasing:
+		x := linear() - 1
+		return math.Sqrt(1 - x*x)
+	case ggrSphereDecreasing:
+		x := linear()
+		return 1 - math.Sqrt(1-x*x)
+	}
+	return linear()
+}
+
+// stops are the gradient stops the segment blends through. A linear blend
+// is exactly its ends and middle; the curves are sampled.
+func (s ggrSegment) stops() []gradientStop {
+	stop := func(pos, f float64) gradientStop {
+		c := func(i int) float64 { return (s.From[i] + (s.To[i]-s.From[i])*f) * 255 }
+		return gradientStop{At: s.Left + (s.Right-s.Left)*pos, R: c(0), G: c(1), B: c(2)}
+	}
+	mid := (s.Mid - s.Left) / (s.Right - s.Left)
+	switch s.Blend {
+	case ggrLinear:
+		return []gradientStop{stop(0, 0), stop(mid, 0.5), stop(1, 1)}
+	case ggrStep:
+		return []gradientStop{stop(0, 0), stop(mid, 0), stop(mid, 1), stop(1, 1)}
+	}
+	var stops []gradientStop
+	for i := 0; i <= ggrSamples; i++ {
+		pos := float64(i) / ggrSamples
+		if prev := float64(i-1) / ggrSamples; i > 0 && prev < mid && mid < pos {
+			stops = append(stops, stop(mid, s.factor(mid)))
+		}
+		stops = append(stops, stop(pos, s.factor(pos)))
+	}
+	return stops
+}
+
+// parseGGR parses a GIMP gradient: the header, an optional name, the
+// number of segments and then one segment per line, each the left, middle
+// and right positions, the left and right colors as red, green, blue and
+// alpha from 0 to 1, the blending function and the coloring, and in newer
+// files the left and right color types. Alpha is ignored, and only RGB
+// coloring is supported.
+func parseGGR(text string) (gradient, error) {
+	lines := strings.Split(text, "\n")
+	if strings.TrimSpace(lines[0]) != ggrHeader {
+		return gradient{}, errors.Errorf("line 1: not a GIMP gradient; it must start %q", ggrHeader)
+	}
+	n := 1
+	if n < len(lines) && strings.HasPrefix(lines[n], "Name:") {
+		n++
+	}
+	if n >= len(lines) {
+		return gradient{}, errors.Errorf("line %d: missing the number of segments", n+1)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(lines[n]))
+	if err != nil || count < 1 {
+		return gradient{}, errors.Errorf("line %d: %q is not a number of segments", n+1, lines[n])
+	}
+	n++
+	if len(lines)-n < count || strings.TrimSpace(lines[n+count-1]) == "" {
+		return gradient{}, errors.Errorf("line %d: expected %d segments", n+1, count)
+	}
+
+	var stops []gradientStop
+	previous := 0.0
+	for i := 0; i < count; i++ {
+		line := n + i + 1
+		fields := strings.Fields(lines[n+i])
+		if len(fields) != 13 && len(fields) != 15 {
+			return gradient{}, errors.Errorf("line %d: a segment has 13 or 15 fields, not %d", line, len(fields))
+		}
+		var v [11]float64
+		for j := range v {
+			if v[j], err = strconv.ParseFloat(fields[j], 64); err != nil {
+				return gradient{}, errors.Errorf("line %d: field %d, %q, is not a number", line, j+1, fields[j])
+			}
+		}
+		blend, err := strconv.Atoi(fields[11])
+		if err != nil || blend < ggrLinear || blend > ggrStep {
+			return gradient{}, errors.Errorf("line %d: unknown blending function %q", line, fields[11])
+		}
+		if fields[12] != "0" {
+			return gradient{}, errors.Errorf("line %d: only RGB coloring is supported, not %q", line, fields[12])
+		}
+		seg := ggrSegment{
+			Left: v[0], Mid: v[1], Right: v[2],
+			From:  [3]float64{v[3], v[4], v[5]},
+			To:    [3]float64{v[7], v[8], v[9]},
+			Blend: blend,
+		}
+		if !(seg.Left <= seg.Mid && seg.Mid <= seg.Right && seg.Left < seg.Right) {
+			return gradient{}, errors.Errorf("line %d: the positions %g, %g, %g are out of order", line, seg.Left, seg.Mid, seg.Right)
+		}
+		if math.Abs(seg.Left-previous) > 1e-6 {
+			return gradient{}, errors.Errorf("line %d: the segment starts at %g, not where the last ended, %g", line, seg.Left, previous)
+		}
+		for _, c := range append(seg.From[:], seg.To[:]...) {
+			if c < 0 || c > 1 {
+				return gradient{}, errors.Errorf("line %d: the color channel %g is outside 0 to 1", line, c)
+			}
+		}
+		previous = seg.Right
+		stops = append(stops, seg.stops()...)
+	}
+	if math.Abs(previous-1) > 1e-6 {
+		return gradient{}, errors.Errorf("the segments end at %g, not 1", previous)
+	}
+	return gradient{Stops: stops}, nil
+}
+
+// parseHexStops parses a list of #rrggbb stops separated by commas or
+// newlines, each with an optional position as a percentage. As in CSS, a
+// missing first or last position is 0% or 100%, and stops without one in
+// between are spread evenly.
+func parseHexStops(text string) (gradient, error) {
+	var stops []gradientStop
+	var known []bool
+	for _, line := range strings.Split(text, "\n") {
+		for _, item := range strings.Split(line, ",") {
+			fields := strings.Fields(item)
+			if len(fields) == 0 {
+				continue
+			}
+			n := len(stops) + 1
+			if len(fields) > 2 {
+				return gradient{}, errors.Errorf("stop %d: %q is not a color and a position", n, strings.TrimSpace(item))
+			}
+			c, err := parseHexColor(fields[0])
+			if err != nil {
+				return gradient{}, errors.Wrapf(err, "stop %d", n)
+			}
+			stop := gradientStop{R: float64(c.R), G: float64(c.G), B: float64(c.B)}
+			if len(fields) == 2 {
+				at, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+				if err != nil || !strings.HasSuffix(fields[1], "%") || at < 0 || at > 100 {
+					return gradient{}, errors.Errorf("stop %d: %q is not a position from 0%% to 100%%", n, fields[1])
+				}
+				stop.At = at / 100
+			}
+			stops = append(stops, stop)
+			known = append(known, len(fields) == 2)
+		}
+	}
+	if len(stops) < 2 {
+		return gradient{}, errors.Errorf("a gradient needs at least two stops, not %d", len(stops))
+	}
+
+	if !known[0] {
+		stops[0].At, known[0] = 0, true
+	}
+	if last := len(stops) - 1; !known[last] {
+		stops[last].At, known[last] = 1, true
+	}
+	from := 0
+	for i := 1; i < len(stops); i++ {
+		if !known[i] {
+			continue
+		}
+		if stops[i].At < stops[from].At {
+			return gradient{}, errors.Errorf("stop %d at %g%% comes before stop %d at %g%%", i+1, stops[i].At*100, from+1, stops[from].At*100)
+		}
+		for j := from + 1; j < i; j++ {
+			stops[j].At = stops[from].At + (stops[i].At-stops[from].At)*float64(j-from)/float64(i-from)
+		}
+		from = i
+	}
+	return gradient{Stops: stops}, nil
+}
+
+// paletteFile is the palette of a -palette-file, which the reload key can
+// replace while frames render.
+type paletteFile struct {
+	path    string
+	current atomic.Value // a Palette
+}
+
+func (f *paletteFile) Palette() Palette {
+	return f.current.Load().(Palette)
+}
+
+func (f *paletteFile) Color(t float64) (float64, float64, float64) {
+	return f.Palette().Color(t)
+}
+
+// Reload reads the file again, keeping the palette it had if it no longer
+// loads.
+func (f *paletteFile) Reload() error {
+	p, err := LoadPalette(f.path)
+	if err != nil {
+		return err
+	}
+	f.current.Store(p)
+	atomic.AddInt64(&paletteRevision, 1)
+	return nil
+}
+
+// registerPaletteFile loads the palette at path and adds it to palettes,
+// named after the file without its extension.
+func registerPaletteFile(path string) (string, *paletteFile, error) {
+	f := &paletteFile{path: path}
+	if err := f.Reload(); err != nil {
+		return "", nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, p := range palettes {
+		if p.Name == name {
+			return "", nil, errors.Errorf("%s: there is already a palette named %q", path, name)
+		}
+	}
+	palettes = append(palettes, paletteRegistration{Name: name, Palette: f})
+	return name, f, nil
+}
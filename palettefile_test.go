@@ -0,0 +1,185 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadPalette loads the sample GIMP gradient and hex-stop list,
+// checking colors where the file pins them down.
+func TestLoadPalette(t *testing.T) {
+	// the sine segment at a sample, three sixteenths of the way across
+	sine := (math.Sin(-math.Pi/2+math.Pi*3/16) + 1) / 2
+	tests := []struct {
+		path string
+		at   []float64
+		want [][3]float64
+	}{
+		{
+			path: filepath.Join("testdata", "sunset.ggr"),
+			at:   []float64{0, 0.125, 0.25, 0.5, 0.5 + 0.5*3/16, 0.75, 1},
+			want: [][3]float64{
+				{0, 0, 51}, {63.75, 12.75, 38.25}, {127.5, 25.5, 25.5}, {255, 51, 0},
+				{255, (0.2 + 0.6*sine) * 255, 0}, {255, 127.5, 0}, {255, 204, 0},
+			},
+		},
+		{
+			// the third stop has no position, so it goes halfway between
+			// its neighbors
+			path: filepath.Join("testdata", "ocean.txt"),
+			at:   []float64{0, 0.125, 0.25, 0.625, 1},
+			want: [][3]float64{{0, 0, 0}, {0, 0, 127.5}, {0, 0, 255}, {0, 255, 0}, {255, 255, 255}},
+		},
+	}
+	for _, tt := range tests {
+		p, err := LoadPalette(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, at := range tt.at {
+			r, g, b := p.Color(at)
+			got := [3]float64{r, g, b}
+			for c := range got {
+				if math.Abs(got[c]-tt.want[i][c]) > 1e-9 {
+					t.Errorf("%s at %v is %v; want %v", tt.path, at, got, tt.want[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestLoadPaletteErrors checks that malformed palette files are rejected
+// with where they went wrong, and a missing one as an IO error.
+func TestLoadPaletteErrors(t *testing.T) {
+	const seg = "0 0.5 1 0 0 0 1 1 1 1 1 0 0"
+	tests := []struct {
+		name, body, want string
+	}{
+		{"header.ggr", "Name: x\n1\n" + seg + "\n", "line 1: not a GIMP gradient"},
+		{"count.ggr", "GIMP Gradient\nmany\n", `line 2: "many" is not a number of segments`},
+		{"short.ggr", "GIMP Gradient\nName: x\n2\n" + seg + "\n", "line 4: expected 2 segments"},
+		{"fields.ggr", "GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1 1 1 1 0\n", "line 3: a segment has 13 or 15 fields, not 12"},
+		{"number.ggr", "GIMP Gradient\n1\n0 half 1 0 0 0 1 1 1 1 1 0 0\n", `line 3: field 2, "half", is not a number`},
+		{"blend.ggr", "GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1 1 1 1 7 0\n", `line 3: unknown blending function "7"`},
+		{"hsv.ggr", "GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1 1 1 1 0 1\n", `line 3: only RGB coloring is supported, not "1"`},
+		{"order.ggr", "GIMP Gradient\n1\n0 0.8 0.5 0 0 0 1 1 1 1 1 0 0\n", "line 3: the positions 0, 0.8, 0.5 are out of order"},
+		{"gap.ggr", "GIMP Gradient\n2\n0 0.25 0.5 0 0 0 1 1 1 1 1 0 0\n0.6 0.8 1 0 0 0 1 1 1 1 1 0 0\n", "line 4: the segment starts at 0.6, not where the last ended, 0.5"},
+		{"channel.ggr", "GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1.5 1 1 1 0 0\n", "line 3: the color channel 1.5 is outside 0 to 1"},
+		{"end.ggr", "GIMP Gradient\n1\n0 0.25 0.5 0 0 0 1 1 1 1 1 0 0\n", "the segments end at 0.5, not 1"},
+		{"one.txt", "#000000\n", "a gradient needs at least two stops, not 1"},
+		{"color.txt", "#00000g, #ffffff", `stop 1: "#00000g" is not a #rrggbb color`},
+		{"percent.txt", "#000000 50, #ffffff", `stop 1: "50" is not a position from 0% to 100%`},
+		{"range.txt", "#000000, #ffffff 150%", `stop 2: "150%" is not a position from 0% to 100%`},
+		{"backwards.txt", "#000000 60%, #808080, #ffffff 30%", "stop 3 at 30% comes before stop 1 at 60%"},
+		{"extra.txt", "#000000 0% dark, #ffffff", `stop 1: "#000000 0% dark" is not a color and a position`},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadPalette(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v; want an error with %q", tt.name, err, tt.want)
+		} else if !strings.Contains(err.Error(), path) {
+			t.Errorf("%s: %v doesn't name the file", tt.name, err)
+		}
+	}
+
+	_, err := LoadPalette(filepath.Join(dir, "missing.ggr"))
+	if err == nil || exitCodeFor(err) != exitIO {
+		t.Errorf("a missing palette gave %v; want an IO error", err)
+	}
+}
+
+// TestGGRStep checks that a step segment makes a hard edge that every
+// interpolation mode keeps.
+func TestGGRStep(t *testing.T) {
+	g, err := parseGGR("GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1 1 1 1 5 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mode := InterpolationLinear; mode < interpolationCount; mode++ {
+		g.Mode = mode
+		if r, _, _ := g.Color(0.5 - 1e-9); r != 0 {
+			t.Errorf("%v just before the step is %v; want 0", mode, r)
+		}
+		if r, _, _ := g.Color(0.5 + 1e-9); math.Abs(r-255) > 1e-6 {
+			t.Errorf("%v just after the step is %v; want 255", mode, r)
+		}
+	}
+}
+
+// TestPaletteFileReload registers a palette file, edits it and reloads it,
+// checking that the colors and the color table follow the file.
+func TestPaletteFileReload(t *testing.T) {
+	defer func(saved []paletteRegistration) { palettes = saved }(palettes)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mine.txt")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("#000000, #ffffff")
+	name, f, err := registerPaletteFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "mine" {
+		t.Errorf("the palette is named %q; want mine", name)
+	}
+	if got, err := parsePalette("mine"); err != nil || got != "mine" {
+		t.Errorf("the registered palette parsed as %q, %v", got, err)
+	}
+
+	settings := testSettings(8, 8)
+	settings.Palette = name
+	color := func() [3]float64 {
+		r, g, b := paletteFor(&settings).Color(0.5)
+		return [3]float64{r, g, b}
+	}
+	if got := color(); got != [3]float64{127.5, 127.5, 127.5} {
+		t.Errorf("midway is %v; want gray", got)
+	}
+	before := colorKeyFor(&settings)
+
+	write("#ff0000, #ff0000")
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := color(); got != [3]float64{255, 0, 0} {
+		t.Errorf("midway after the reload is %v; want red", got)
+	}
+	if colorKeyFor(&settings) == before {
+		t.Error("the reload didn't invalidate the color table")
+	}
+
+	write("#ff0000")
+	if err := f.Reload(); err == nil {
+		t.Error("reloading a broken file succeeded")
+	}
+	if got := color(); got != [3]float64{255, 0, 0} {
+		t.Errorf("a failed reload changed the palette to %v", got)
+	}
+
+	settings.Interpolation = InterpolationSmoothstep
+	if g, ok := paletteFor(&settings).(gradient); !ok || g.Mode != InterpolationSmoothstep {
+		t.Errorf("the interpolation didn't reach the file's gradient: %#v", paletteFor(&settings))
+	}
+
+	gold := filepath.Join(dir, "gold.txt")
+	if err := os.WriteFile(gold, []byte("#000000, #ffffff"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := registerPaletteFile(gold); err == nil {
+		t.Error("a file named after a built-in palette registered")
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	panelScale   = 2
+	panelPadding = 8
+	panelLine    = (glyphHeight + 4) * panelScale
+)
+
+// panelParam is one editable line of the parameter panel. Adjust is called
+// with -1 or +1 and reports whether the image needs updating; for
+// RecolorOnly parameters that means re-coloring the cached iterations
+// rather than re-rendering.
+type panelParam struct {
+	Name        string
+	Value       func(s *Settings) string
+	Adjust      func(s *Settings, dir int) bool
+	RecolorOnly bool
+}
+
+// paramPanel is a key-driven overlay menu: up and down select a parameter,
+// left and right adjust it.
+type paramPanel struct {
+	Visible  bool
+	selected int
+	params   []panelParam
+}
+
+func newParamPanel() *paramPanel {
+	p := &paramPanel{
+		params: []panelParam{
+			{
+				Name:  "Iterations",
+				Value: func(s *Settings) string { return fmt.Sprint(s.MaxIterations) },
+				Adjust: func(s *Settings, dir int) bool {
+					return s.AdjustIterations(int64(dir) * s.IterationStep)
+				},
+			},
+			{
+				Name:  "Zoom factor",
+				Value: func(s *Settings) string { return fmt.Sprintf("%gx", s.ZoomFactor) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.StepZoomFactor(dir)
+					return false
+				},
+			},
+			{
+				Name:  "Fractal",
+				Value: func(s *Settings) string { return kernelName(s) },
+				Adjust: func(s *Settings, dir int) bool {
+					return cycleKernel(s, dir)
+				},
+			},
+			{
+				Name:  "Keep view",
+				Value: func(s *Settings) string { return onOff(s.KeepView) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.KeepView = !s.KeepView
+					return false
+				},
+			},
+			{
+				Name:  "Period check",
+				Value: func(s *Settings) string { return onOff(s.PeriodDetection) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.PeriodDetection = !s.PeriodDetection
+					return true
+				},
+			},
+			{
+				Name: "Bailout",
+				Value: func(s *Settings) string {
+					if s.ComponentBailout {
+						return "components"
+					}
+					return "modulus"
+				},
+				Adjust: func(s *Settings, dir int) bool {
+					s.ComponentBailout = !s.ComponentBailout
+					return true
+				},
+			},
+			{
+				Name:  "Palette",
+				Value: paletteName,
+				Adjust: func(s *Settings, dir int) bool {
+					cyclePalette(s, dir)
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Interpolation",
+				Value: func(s *Settings) string { return s.Interpolation.String() },
+				Adjust: func(s *Settings, dir int) bool {
+					s.Interpolation = (s.Interpolation + Interpolation(dir) + interpolationCount) % interpolationCount
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Tone map",
+				Value: func(s *Settings) string { return s.ToneMap.String() },
+				Adjust: func(s *Settings, dir int) bool {
+					s.ToneMap = (s.ToneMap + ToneMap(dir) + toneMapCount) % toneMapCount
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Invert",
+				Value: func(s *Settings) string { return onOff(s.Invert) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.Invert = !s.Invert
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Smooth",
+				Value: func(s *Settings) string { return onOff(s.SmoothColoring) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.SmoothColoring = !s.SmoothColoring
+					return true
+				},
+			},
+			{
+				Name:  "Smooth blend",
+				Value: func(s *Settings) string { return fmt.Sprintf("%.1f", s.SmoothBlend) },
+				Adjust: func(s *Settings, dir int) bool {
+					return s.AdjustSmoothBlend(dir)
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Color density",
+				Value: func(s *Settings) string { return fmt.Sprint(s.ColorDensity) },
+				Adjust: func(s *Settings, dir int) bool {
+					return s.AdjustColorDensity(dir)
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Color offset",
+				Value: func(s *Settings) string { return fmt.Sprintf("%.2f", s.ColorOffset) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.ShiftColorOffset(dir)
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Color cutoff",
+				Value: func(s *Settings) string { return fmt.Sprint(s.MinColorThreshold) },
+				Adjust: func(s *Settings, dir int) bool {
+					if s.MinColorThreshold+float64(dir)*5 < 0 {
+						return false
+					}
+					s.MinColorThreshold += float64(dir) * 5
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Dither",
+				Value: func(s *Settings) string { return onOff(s.Dither) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.Dither = !s.Dither
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Linear light",
+				Value: func(s *Settings) string { return onOff(s.LinearLight) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.LinearLight = !s.LinearLight
+					return true
+				},
+				RecolorOnly: true,
+			},
+			{
+				Name:  "Edge AA",
+				Value: func(s *Settings) string { return onOff(s.AdaptiveAA) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.AdaptiveAA = !s.AdaptiveAA
+					return true
+				},
+			},
+			{
+				Name:  "AA samples",
+				Value: func(s *Settings) string { return fmt.Sprintf("%dx%d", s.AASamples, s.AASamples) },
+				Adjust: func(s *Settings, dir int) bool {
+					n := s.AASamples + int64(dir)
+					if n < 2 || n > 8 {
+						return false
+					}
+					s.AASamples = n
+					return s.AdaptiveAA
+				},
+			},
+			{
+				Name:  "Edge threshold",
+				Value: func(s *Settings) string { return fmt.Sprint(s.EdgeThreshold) },
+				Adjust: func(s *Settings, dir int) bool {
+					if s.EdgeThreshold+float64(dir) < 0 {
+						return false
+					}
+					s.EdgeThreshold += float64(dir)
+					return s.AdaptiveAA
+				},
+			},
+			{
+				Name:  "Detail region",
+				Value: func(s *Settings) string { return fmt.Sprintf("%dpx", s.DetailRegion) },
+				Adjust: func(s *Settings, dir int) bool {
+					if n := s.DetailRegion + 16*int64(dir); n >= 16 && n <= 512 {
+						s.DetailRegion = n
+					}
+					return false
+				},
+			},
+			{
+				Name:  "Blur radius",
+				Value: func(s *Settings) string { return fmt.Sprint(s.BlurRadius) },
+				Adjust: func(s *Settings, dir int) bool {
+					if n := s.BlurRadius + int64(dir); n >= 0 && n <= 16 {
+						s.BlurRadius = n
+					}
+					return false
+				},
+			},
+			{
+				Name:  "Relief",
+				Value: func(s *Settings) string { return onOff(s.Relief) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.Relief = !s.Relief
+					return false
+				},
+			},
+			{
+				Name:  "Light azimuth",
+				Value: func(s *Settings) string { return fmt.Sprint(s.LightAzimuth) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.LightAzimuth = math.Mod(s.LightAzimuth+15*float64(dir)+360, 360)
+					return false
+				},
+			},
+			{
+				Name:  "Light elevation",
+				Value: func(s *Settings) string { return fmt.Sprint(s.LightElevation) },
+				Adjust: func(s *Settings, dir int) bool {
+					if n := s.LightElevation + 15*float64(dir); n >= 0 && n <= 90 {
+						s.LightElevation = n
+					}
+					return false
+				},
+			},
+			{
+				Name:  "Contours",
+				Value: func(s *Settings) string { return onOff(s.Contours) },
+				Adjust: func(s *Settings, dir int) bool {
+					s.Contours = !s.Contours
+					return false
+				},
+			},
+			{
+				Name:  "Contour spacing",
+				Value: func(s *Settings) string { return fmt.Sprint(s.ContourSpacing) },
+				Adjust: func(s *Settings, dir int) bool {
+					if n := s.ContourSpacing + int64(dir); n >= 1 {
+						s.ContourSpacing = n
+					}
+					return false
+				},
+			},
+			{
+				Name:  "Explore window",
+				Value: func(s *Settings) string { return fmt.Sprint(s.VarianceWindow) },
+				Adjust: func(s *Settings, dir int) bool {
+					if s.VarianceWindow+int64(dir)*8 < 8 {
+						return false
+					}
+					s.VarianceWindow += int64(dir) * 8
+					return false
+				},
+			},
+		},
+	}
+	p.params = append(p.params, toneCurveParams()...)
+	return p
+}
+
+func (p *paramPanel) Toggle() {
+	p.Visible = !p.Visible
+}
+
+// HandleKey applies a key press to the panel. handled is false for keys
+// the panel doesn't use; rerender and recolor report what the change needs.
+func (p *paramPanel) HandleKey(keyCode sdl.Keycode, settings *Settings) (handled, rerender, recolor bool) {
+	dir := 0
+	switch keyCode {
+	case sdl.K_UP:
+		p.selected = (p.selected + len(p.params) - 1) % len(p.params)
+		return true, false, false
+	case sdl.K_DOWN:
+		p.selected = (p.selected + 1) % len(p.params)
+		return true, false, false
+	case sdl.K_LEFT:
+		dir = -1
+	case sdl.K_RIGHT:
+		dir = 1
+	default:
+		return false, false, false
+	}
+
+	param := p.params[p.selected]
+	if !param.Adjust(settings, dir) {
+		return true, false, false
+	}
+	return true, !param.RecolorOnly, param.RecolorOnly
+}
+
+func (p *paramPanel) Draw(renderer *sdl.Renderer, settings *Settings) error {
+	if !p.Visible {
+		return nil
+	}
+
+	lines := make([]string, len(p.params))
+	var width int32
+	for i, param := range p.params {
+		lines[i] = fmt.Sprintf("%-15s %s", param.Name, param.Value(settings))
+		if w := textWidth(lines[i], panelScale); w > width {
+			width = w
+		}
+	}
+
+	err := renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	renderer.SetDrawColor(0, 0, 0, 180)
+	err = renderer.FillRect(&sdl.Rect{
+		X: panelPadding,
+		Y: panelPadding,
+		W: width + 2*panelPadding,
+		H: int32(len(lines))*panelLine + 2*panelPadding,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, line := range lines {
+		if i == p.selected {
+			renderer.SetDrawColor(255, 220, 0, 255)
+		} else {
+			renderer.SetDrawColor(255, 255, 255, 255)
+		}
+		err = drawText(renderer, 2*panelPadding, 2*panelPadding+int32(i)*panelLine, panelScale, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// viewParams are the settings that reproduce a view, in the form they are
+// printed and passed back on the command line.
+type viewParams struct {
+	Kernel        string  `json:"kernel"`
+	Formula       string  `json:"formula,omitempty"`
+	CenterX       float64 `json:"center_x"`
+	CenterY       float64 `json:"center_y"`
+	Min           float64 `json:"min"`
+	Max           float64 `json:"max"`
+	MaxIterations int64   `json:"iterations"`
+	Rotation      float64 `json:"rotation,omitempty"`
+	ToneCurve     string  `json:"tone_curve,omitempty"`
+	Palette       string  `json:"palette,omitempty"`
+	Smooth        bool    `json:"smooth,omitempty"`
+	Julia         bool    `json:"julia,omitempty"`
+	JuliaX        float64 `json:"julia_x,omitempty"`
+	JuliaY        float64 `json:"julia_y,omitempty"`
+	Magnification float64 `json:"magnification"`
+}
+
+func paramsFor(settings *Settings) viewParams {
+	return viewParams{
+		Kernel:        kernelName(settings),
+		Formula:       settings.Formula,
+		CenterX:       settings.Center.X,
+		CenterY:       settings.Center.Y,
+		Min:           settings.Min,
+		Max:           settings.Max,
+		MaxIterations: settings.MaxIterations,
+		Rotation:      settings.Rotation,
+		ToneCurve:     settings.ToneCurve.String(),
+		Palette:       settings.Palette,
+		Smooth:        settings.SmoothColoring,
+		Julia:         settings.Fractal == FractalJulia,
+		JuliaX:        settings.Julia.X,
+		JuliaY:        settings.Julia.Y,
+		Magnification: settings.Magnification(),
+	}
+}
+
+// CommandLine renders the parameters as flags that relaunch the view.
+func (p viewParams) CommandLine() string {
+	args := []string{
+		"-kernel", p.Kernel,
+		"-center-x", fmt.Sprint(p.CenterX),
+		"-center-y", fmt.Sprint(p.CenterY),
+		"-min", fmt.Sprint(p.Min),
+		"-max", fmt.Sprint(p.Max),
+		"-iterations", fmt.Sprint(p.MaxIterations),
+	}
+	if p.Rotation != 0 {
+		args = append(args, "-rotation", fmt.Sprint(p.Rotation))
+	}
+	if p.ToneCurve != "" {
+		args = append(args, "-tone-curve", p.ToneCurve)
+	}
+	if p.Palette != "" {
+		args = append(args, "-palette", p.Palette)
+	}
+	if p.Smooth {
+		args = append(args, "-smooth")
+	}
+	if p.Julia {
+		args = append(args, "-julia", "-julia-x", fmt.Sprint(p.JuliaX), "-julia-y", fmt.Sprint(p.JuliaY))
+	}
+	if p.Formula != "" {
+		args = append(args, "-formula", fmt.Sprintf("%q", p.Formula))
+	}
+	return strings.Join(args, " ")
+}
+
+func (p viewParams) JSON() (string, error) {
+	b, err := json.Marshal(p)
+	return string(b), err
+}
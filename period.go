@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math/cmplx"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	// maxProbePeriod is the longest cycle orbitPeriod looks for.
+	maxProbePeriod = 1024
+	// probeEpsilon is how close, relative to its size, an orbit must come
+	// back to count as a cycle.
+	probeEpsilon = 1e-9
+)
+
+// orbitPeriod iterates the orbit of the point p with settings' kernel,
+// seeded as the renderer seeds it, and, if it stays bounded for
+// MaxIterations, looks for the length of the cycle it has settled into. It
+// returns 0 when the orbit escapes or no cycle up to maxProbePeriod is
+// found.
+func orbitPeriod(p complex128, settings *Settings) (period int, escaped bool, err error) {
+	kernel, err := kernelFor(settings)
+	if err != nil {
+		return 0, false, err
+	}
+	c, z := seed(real(p), imag(p), settings)
+	_, z, escaped = kernel.Iterate(c, z)
+	if escaped {
+		return 0, true, nil
+	}
+
+	// a one-iteration kernel steps the orbit a single iteration at a time
+	single := settings.Clone()
+	single.MaxIterations = 1
+	single.PeriodDetection = false
+	step, err := kernelFor(&single)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ref := z
+	tolerance := probeEpsilon * (1 + cmplx.Abs(ref))
+	for n := 1; n <= maxProbePeriod; n++ {
+		_, z, escaped = step.Iterate(c, z)
+		if escaped {
+			return 0, true, nil
+		}
+		if cmplx.Abs(z-ref) < tolerance {
+			return n, false, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// bulbName names the part of the set whose points have the given period.
+func bulbName(period int) string {
+	if period == 1 {
+		return "main cardioid"
+	}
+	return fmt.Sprintf("period-%d bulb", period)
+}
+
+// periodProbe reports the period of the orbit under a clicked point.
+type periodProbe struct {
+	Active bool
+	marker *Point
+	Label  string
+}
+
+func (p *periodProbe) Toggle() {
+	p.Active = !p.Active
+	p.marker = nil
+	p.Label = ""
+}
+
+// Probe finds the period of the orbit under the pixel (px, py).
+func (p *periodProbe) Probe(px, py float64, settings *Settings) {
+	re, im := settings.PixelToComplex(px, py)
+	period, escaped, err := orbitPeriod(complex(re, im), settings)
+	if err != nil {
+		log.WithError(err).Error("could not probe the orbit")
+		return
+	}
+
+	p.marker = &Point{X: px, Y: py}
+	switch {
+	case escaped:
+		p.Label = "escapes; not in the set"
+	case period == 0:
+		p.Label = fmt.Sprintf("no cycle up to %d found", maxProbePeriod)
+	case settings.Fractal == FractalJulia || kernelName(settings) != "mandelbrot":
+		// the bulbs are those of the Mandelbrot set
+		p.Label = fmt.Sprintf("period %d", period)
+	default:
+		p.Label = fmt.Sprintf("period %d: %s", period, bulbName(period))
+	}
+	log.WithFields(log.Fields{"re": re, "im": im, "period": period, "escaped": escaped}).Info("probed the orbit")
+}
+
+// Draw marks the probed point.
+func (p *periodProbe) Draw(renderer *sdl.Renderer) error {
+	if p.marker == nil {
+		return nil
+	}
+
+	err := renderer.SetDrawColor(255, 200, 0, 255)
+	if err != nil {
+		return err
+	}
+	defer renderer.SetDrawColor(0, 0, 0, 255)
+
+	x, y := int32(p.marker.X), int32(p.marker.Y)
+	err = renderer.DrawLine(x-4, y, x+4, y)
+	if err != nil {
+		return err
+	}
+	return renderer.DrawLine(x, y-4, x, y+4)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestProbeLabel probes the origin, a fixed point of every kernel, checking
+// that only the Mandelbrot set's own bulbs are named.
+func TestProbeLabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *Settings)
+		want  string
+	}{
+		{name: "mandelbrot", setup: func(s *Settings) {}, want: "period 1: main cardioid"},
+		{name: "tricorn", setup: func(s *Settings) { s.Kernel = "tricorn" }, want: "period 1"},
+		{name: "formula", setup: func(s *Settings) { s.Kernel = "formula"; s.Formula = "z^3+c" }, want: "period 1"},
+		{name: "julia", setup: func(s *Settings) { s.Fractal = FractalJulia; s.Julia = Point{} }, want: "period 1"},
+	}
+	for _, tt := range tests {
+		settings := testSettings(64, 64)
+		tt.setup(&settings)
+		px, py := settings.ComplexToPixel(0, 0)
+
+		var probe periodProbe
+		probe.Probe(px, py, &settings)
+		if probe.Label != tt.want {
+			t.Errorf("%s: the origin is labelled %q; want %q", tt.name, probe.Label, tt.want)
+		}
+	}
+}
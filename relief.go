@@ -0,0 +1,69 @@
+package main
+
+import "math"
+
+const (
+	reliefAmbient  = 0.3
+	reliefStrength = 10
+)
+
+// Shaded returns a copy of src lit as if the iteration counts were a
+// height field, with the light coming from azimuth and elevation degrees.
+// Heights are log-scaled so the steep climb towards the set doesn't
+// swamp everything else. src is a frame in the layout of Pixels; the
+// returned slice is reused by the next call.
+func (mi *MandelbrotImage) Shaded(src []byte, azimuth, elevation float64) []byte {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if len(mi.shaded) != len(src) {
+		mi.shaded = make([]byte, len(src))
+	}
+
+	az := azimuth * math.Pi / 180
+	el := elevation * math.Pi / 180
+	lx := math.Cos(el) * math.Cos(az)
+	ly := math.Cos(el) * math.Sin(az)
+	lz := math.Sin(el)
+
+	width := int(mi.Width)
+	height := int(mi.Height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			nx, ny, nz := heightNormal(mi.Iterations, width, height, x, y)
+			light := math.Max(0, nx*lx+ny*ly+nz*lz)
+			factor := reliefAmbient + (1-reliefAmbient)*light
+
+			idx := (y*width + x) * 4
+			for ch := 0; ch < 3; ch++ {
+				mi.shaded[idx+ch] = uint8(math.Min(255, float64(src[idx+ch])*factor))
+			}
+			mi.shaded[idx+3] = src[idx+3]
+		}
+	}
+	return mi.shaded
+}
+
+// heightNormal is the unit surface normal at (x, y) of the log-scaled
+// height field of iters, with x to the right, y down the image and z out
+// of it. Neighbours past the edges are clamped.
+func heightNormal(iters []int64, width, height, x, y int) (float64, float64, float64) {
+	at := func(x, y int) float64 {
+		if x < 0 {
+			x = 0
+		} else if x >= width {
+			x = width - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= height {
+			y = height - 1
+		}
+		return math.Log1p(float64(iters[y*width+x]))
+	}
+
+	nx := -(at(x+1, y) - at(x-1, y)) * reliefStrength / 2
+	ny := -(at(x, y+1) - at(x, y-1)) * reliefStrength / 2
+	length := math.Sqrt(nx*nx + ny*ny + 1)
+	return nx / length, ny / length, 1 / length
+}
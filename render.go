@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rendered is the output of renderImage: the image as it would appear on
+// screen, the same at 16 bits per channel if asked for, the iteration
+// count of every pixel in rows, and statistics.
+type rendered struct {
+	Image      *image.RGBA
+	Deep       *image.NRGBA64
+	Iterations []int64
+	Stats      renderStats
+}
+
+// Output is the image to export with the given options.
+func (r *rendered) Output(export exportOptions) image.Image {
+	if export.BitDepth == 16 && r.Deep != nil {
+		return r.Deep
+	}
+	return r.Image
+}
+
+// renderStats summarises a render for -stats.
+type renderStats struct {
+	Iterations       int64   `json:"total_iterations"`
+	WallTimeMS       float64 `json:"wall_time_ms"`
+	Pixels           int64   `json:"pixels"`
+	InteriorFraction float64 `json:"interior_fraction"`
+	ExteriorFraction float64 `json:"exterior_fraction"`
+	MinIterations    int64   `json:"min_iterations"`
+	MaxIterations    int64   `json:"max_iterations"`
+	MeanIterations   float64 `json:"mean_iterations"`
+}
+
+// writeStats writes stats as one line of JSON; a nil w discards them.
+func writeStats(w io.Writer, stats renderStats) error {
+	if w == nil {
+		return nil
+	}
+	return errors.Wrap(json.NewEncoder(w).Encode(stats), "could not write render statistics")
+}
+
+// renderImage renders settings synchronously, without a window, and with
+// deep set also at 16 bits per channel.
+func renderImage(settings *Settings, deep bool) (*rendered, error) {
+	kernel, err := kernelFor(settings)
+	if err != nil {
+		return nil, err
+	}
+	colors := newColorTable(settings)
+	start := time.Now()
+
+	width := int(settings.Width)
+	height := int(settings.Height)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var deepImg *image.NRGBA64
+	if deep {
+		deepImg = image.NewNRGBA64(img.Rect)
+	}
+	iterations := make([]int64, width*height)
+
+	// outside the region of interest the image is left the background
+	bounds := settings.renderBounds()
+	if bounds != img.Rect {
+		bg := settings.BackgroundColor
+		draw.Draw(img, img.Rect, image.NewUniform(color.RGBA{bg.R, bg.G, bg.B, 255}), image.Point{}, draw.Src)
+		if deep {
+			draw.Draw(deepImg, deepImg.Rect, image.NewUniform(color.RGBA{bg.R, bg.G, bg.B, 255}), image.Point{}, draw.Src)
+		}
+	}
+
+	var total, interior int64
+	var mu sync.Mutex
+	lowest, highest := int64(math.MaxInt64), int64(math.MinInt64)
+
+	mirror := symmetricView(settings)
+
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workerCount(settings.Workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			low, high := int64(math.MaxInt64), int64(math.MinInt64)
+			put := func(x, y int, iters int64, nu float64) {
+				px, py := float64(x), float64(y)
+				iterations[y*width+x] = iters
+				red, green, blue := pixelColor(colors, iters, nu, settings)
+				img.SetRGBA(x, y, displayColor(pixelPoint(px, py, red, green, blue, iters, settings)))
+				if deep {
+					deepImg.SetNRGBA64(x, y, deepColor(red, green, blue, iters, settings))
+				}
+
+				atomic.AddInt64(&total, iters)
+				if iters == settings.MaxIterations {
+					atomic.AddInt64(&interior, 1)
+				}
+				if iters < low {
+					low = iters
+				}
+				if iters > high {
+					high = iters
+				}
+			}
+
+			for y := range rows {
+				m, _ := mirrorRow(float64(y), settings.Height)
+				paired := mirror && mirroredRow(m, settings)
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					iters, nu := samplePixelSmooth(kernel, float64(x), float64(y), settings)
+					put(x, y, iters, nu)
+					if paired {
+						put(x, int(m), iters, nu)
+					}
+				}
+			}
+
+			mu.Lock()
+			if low < lowest {
+				lowest = low
+			}
+			if high > highest {
+				highest = high
+			}
+			mu.Unlock()
+		}()
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		// a symmetric view's lower rows come with their upper partners
+		if mirror && mirroredRow(float64(y), settings) {
+			continue
+		}
+		rows <- y
+	}
+	close(rows)
+	wg.Wait()
+
+	pixels := int64(bounds.Dx() * bounds.Dy())
+	stats := renderStats{
+		Iterations: total,
+		WallTimeMS: float64(time.Since(start).Microseconds()) / 1000,
+		Pixels:     pixels,
+	}
+	if pixels > 0 {
+		stats.InteriorFraction = float64(interior) / float64(pixels)
+		stats.ExteriorFraction = 1 - stats.InteriorFraction
+		stats.MinIterations = lowest
+		stats.MaxIterations = highest
+		stats.MeanIterations = float64(total) / float64(pixels)
+	}
+	return &rendered{Image: img, Deep: deepImg, Iterations: iterations, Stats: stats}, nil
+}
+
+// openStats opens the -stats destination: nothing for "", stdout for "-"
+// and otherwise a file. The returned func closes it.
+func openStats(path string) (io.Writer, func(), error) {
+	switch path {
+	case "":
+		return nil, func() {}, nil
+	case "-":
+		return os.Stdout, func() {}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not create statistics file")
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// logOutput is where the log goes: stderr when -stats writes to stdout, so
+// the JSON statistics aren't interleaved with log lines, and stdout otherwise.
+func logOutput(statsPath string) io.Writer {
+	if statsPath == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
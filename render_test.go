@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// TestLogOutput checks that the log moves to stderr when -stats takes
+// stdout, so stdout carries nothing but the statistics.
+func TestLogOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats.json")
+	tests := []struct {
+		stats string
+		want  *os.File
+	}{
+		{"", os.Stdout},
+		{file, os.Stdout},
+		{"-", os.Stderr},
+	}
+	for _, test := range tests {
+		if got := logOutput(test.stats); got != test.want {
+			t.Errorf("logOutput(%q) = %v, want %v", test.stats, got, test.want.Name())
+		}
+		w, closeStats, err := openStats(test.stats)
+		if err != nil {
+			t.Fatalf("openStats(%q): %v", test.stats, err)
+		}
+		closeStats()
+		if test.stats == "-" && w != os.Stdout {
+			t.Errorf("openStats(%q) = %v, want stdout", test.stats, w)
+		}
+	}
+}
+
+// TestRenderWorkers renders the same view headless on one worker and on
+// several, checking the frames are identical and that 0 means one per CPU.
+func TestRenderWorkers(t *testing.T) {
+	if got := workerCount(0); got != runtime.NumCPU() {
+		t.Errorf("workerCount(0) = %d; want one per CPU, %d", got, runtime.NumCPU())
+	}
+	if got := workerCount(3); got != 3 {
+		t.Errorf("workerCount(3) = %d", got)
+	}
+
+	var want *rendered
+	for _, workers := range []int{1, 2, 5} {
+		settings := testSettings(64, 48)
+		settings.Workers = workers
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == nil {
+			want = r
+			continue
+		}
+		if !reflect.DeepEqual(r.Iterations, want.Iterations) {
+			t.Errorf("%d workers took different iteration counts from one", workers)
+		}
+		samePixels(t, r.Image.Pix, want.Image.Pix)
+	}
+}
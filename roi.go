@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/pkg/errors"
+)
+
+// parseROI parses a region of interest given as "x,y,w,h" in pixels.
+func parseROI(s string) (image.Rectangle, error) {
+	var x, y, w, h int
+	if _, err := fmt.Sscanf(s, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil {
+		return image.Rectangle{}, errors.Wrapf(err, "%q is not x,y,w,h", s)
+	}
+	if w < 1 || h < 1 {
+		return image.Rectangle{}, errors.Errorf("region %q is empty", s)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// renderBounds is the part of the image that is rendered: Settings.ROI,
+// or the whole image when no region is set.
+func (s *Settings) renderBounds() image.Rectangle {
+	full := image.Rect(0, 0, int(s.Width), int(s.Height))
+	if s.ROI.Empty() {
+		return full
+	}
+	return s.ROI.Intersect(full)
+}
+
+// inBounds reports whether the pixel (x, y) is rendered.
+func (s *Settings) inBounds(x, y int) bool {
+	return image.Pt(x, y).In(s.renderBounds())
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// samplingStrategy picks the c values a Buddhabrot traces. The trade-offs:
+//
+//   - uniform draws c at random over the whole sampling square. It is
+//     unbiased and simple, but once zoomed in almost no orbit crosses the
+//     view, so most samples are wasted.
+//   - grid places one sample in each cell of a regular grid over the
+//     square, at a random spot within the cell. The even spread gives less
+//     noise than uniform for the same count, but it is just as wasteful
+//     when zoomed, and it uses only the largest square number of samples
+//     that fits in the count.
+//   - metropolis wanders c by Metropolis-Hastings towards orbits that put
+//     many points in view, and weights each orbit by the inverse so the
+//     image still converges on the uniform one. Samples cluster near the
+//     boundary, so zoomed views converge much faster. Successive samples
+//     are correlated, which can show as blotches at low sample counts.
+type samplingStrategy int
+
+const (
+	samplingUniform samplingStrategy = iota
+	samplingGrid
+	samplingMetropolis
+	samplingStrategyCount
+)
+
+const (
+	// metropolisJump is the chance a Metropolis proposal is drawn afresh
+	// over the whole square rather than near the current c, so the walk
+	// can't get stuck in one region.
+	metropolisJump = 0.2
+
+	// metropolisStep is the spread of a nearby proposal relative to the
+	// view's span.
+	metropolisStep = 0.05
+)
+
+func (s samplingStrategy) String() string {
+	switch s {
+	case samplingGrid:
+		return "grid"
+	case samplingMetropolis:
+		return "metropolis"
+	}
+	return "uniform"
+}
+
+func parseSamplingStrategy(name string) (samplingStrategy, error) {
+	var s samplingStrategy
+	for s = 0; s < samplingStrategyCount; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return samplingUniform, errors.Errorf("unknown sampling strategy %q; use uniform, grid or metropolis", name)
+}
+
+// sample traces worker w's share, of workers, of samples c values into t.
+func (s samplingStrategy) sample(t *orbitTracer, rng *rand.Rand, w, workers int, samples int64) {
+	switch s {
+	case samplingGrid:
+		sampleGrid(t, rng, w, workers, samples)
+	case samplingMetropolis:
+		share := samples / int64(workers)
+		if int64(w) < samples%int64(workers) {
+			share++
+		}
+		sampleMetropolis(t, rng, share)
+	default:
+		sampleUniform(t, rng, w, workers, samples)
+	}
+}
+
+// randomC draws c uniformly over the sampling square.
+func randomC(rng *rand.Rand) complex128 {
+	return complex(
+		(rng.Float64()*2-1)*buddhabrotBound,
+		(rng.Float64()*2-1)*buddhabrotBound)
+}
+
+func sampleUniform(t *orbitTracer, rng *rand.Rand, w, workers int, samples int64) {
+	var orbit []complex128
+	for n := int64(w); n < samples; n += int64(workers) {
+		var escape int64
+		orbit, escape = t.trace(randomC(rng), orbit)
+		t.plot(orbit, escape, 1)
+	}
+}
+
+func sampleGrid(t *orbitTracer, rng *rand.Rand, w, workers int, samples int64) {
+	side := int64(math.Sqrt(float64(samples)))
+	cell := 2 * buddhabrotBound / float64(side)
+
+	var orbit []complex128
+	for n := int64(w); n < side*side; n += int64(workers) {
+		gx, gy := float64(n%side), float64(n/side)
+		c := complex(
+			-buddhabrotBound+(gx+rng.Float64())*cell,
+			-buddhabrotBound+(gy+rng.Float64())*cell)
+
+		var escape int64
+		orbit, escape = t.trace(c, orbit)
+		t.plot(orbit, escape, 1)
+	}
+}
+
+func sampleMetropolis(t *orbitTracer, rng *rand.Rand, samples int64) {
+	var current, proposed []complex128
+	var c complex128
+	var escape int64
+	hits := 0
+
+	// start from any orbit that reaches the view; the search counts
+	// against the samples
+	var n int64
+	for ; n < samples && hits == 0; n++ {
+		c = randomC(rng)
+		current, escape = t.trace(c, current)
+		hits = t.hits(current, escape)
+	}
+	if hits == 0 {
+		return
+	}
+
+	step := (t.settings.Max - t.settings.Min) * metropolisStep
+	for ; n < samples; n++ {
+		next := randomC(rng)
+		if rng.Float64() >= metropolisJump {
+			next = c + complex(rng.NormFloat64()*step, rng.NormFloat64()*step)
+		}
+
+		var nextEscape int64
+		proposed, nextEscape = t.trace(next, proposed)
+		nextHits := t.hits(proposed, nextEscape)
+		if nextHits > 0 && rng.Float64() < float64(nextHits)/float64(hits) {
+			c, escape, hits = next, nextEscape, nextHits
+			current, proposed = proposed, current
+		}
+
+		// c is visited in proportion to its hits, so each visit is
+		// weighted by the inverse to stay true to uniform sampling
+		t.plot(current, escape, 1/float64(hits))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestSamplingStrategies traces a Buddhabrot with each sampling strategy,
+// over the whole set and zoomed in on its edge, checking that each puts
+// orbits across the view, and the same ones for the same seed. Zoomed in,
+// uniform and grid waste most of their samples and light about a fifth of
+// the view, where metropolis lights nearly all of it.
+func TestSamplingStrategies(t *testing.T) {
+	views := []struct {
+		view View
+		// lit is the fraction of the cells orbits must cross, and
+		// metropolis the fraction for that strategy
+		lit, metropolis float64
+	}{
+		{view: homeView, lit: 0.5, metropolis: 0.5},
+		{view: View{Min: -0.1, Max: 0.1, Center: Point{X: 0.75, Y: -0.1}}, lit: 0.1, metropolis: 0.75},
+	}
+	for s := samplingUniform; s < samplingStrategyCount; s++ {
+		if parsed, err := parseSamplingStrategy(s.String()); err != nil || parsed != s {
+			t.Errorf("%v parsed as %v, %v", s, parsed, err)
+		}
+
+		for _, v := range views {
+			view := v.view
+			settings := testSettings(32, 32)
+			settings.ApplyView(view)
+			density := buddhabrotDensity(&settings, 20000, 200, s, 1)
+
+			lit := 0
+			for i, d := range density {
+				if d < 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+					t.Fatalf("%v, %v: cell %d has a density of %v", s, view, i, d)
+				}
+				if d > 0 {
+					lit++
+				}
+			}
+			want := v.lit
+			if s == samplingMetropolis {
+				want = v.metropolis
+			}
+			if float64(lit) < want*float64(len(density)) {
+				t.Errorf("%v, %v: orbits crossed only %d of %d cells", s, view, lit, len(density))
+			}
+			if again := buddhabrotDensity(&settings, 20000, 200, s, 1); !reflect.DeepEqual(again, density) {
+				t.Errorf("%v, %v: the same seed traced a different density", s, view)
+			}
+		}
+	}
+
+	if _, err := parseSamplingStrategy("stratified"); err == nil {
+		t.Error("an unknown sampling strategy parsed")
+	}
+}
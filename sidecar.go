@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// version is the application version recorded in sidecars, set at build
+// time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// sidecar is the JSON written next to a saved PNG, holding everything the
+// image was rendered with so -load can restore it.
+type sidecar struct {
+	Version  string   `json:"version"`
+	Palette  string   `json:"palette"`
+	Settings Settings `json:"settings"`
+}
+
+// sidecarPath is where the sidecar of the PNG at path goes.
+func sidecarPath(path string) string {
+	return path + ".json"
+}
+
+// writeSidecar writes the sidecar of the PNG at path.
+func writeSidecar(path string, settings *Settings) error {
+	blob, err := json.MarshalIndent(sidecar{
+		Version:  version,
+		Palette:  paletteName(settings),
+		Settings: *settings,
+	}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not encode the sidecar")
+	}
+	if err := ioutil.WriteFile(sidecarPath(path), append(blob, '\n'), 0o644); err != nil {
+		return errors.Wrap(err, "could not write the sidecar")
+	}
+	return nil
+}
+
+// readSidecar loads and validates the settings in a sidecar.
+func readSidecar(path string) (Settings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Settings{}, errors.Wrap(err, "could not read the sidecar")
+	}
+
+	var car sidecar
+	if err := json.Unmarshal(data, &car); err != nil {
+		return Settings{}, errors.Wrapf(err, "could not parse %s", path)
+	}
+	s := car.Settings
+	if s.Width <= 0 || s.Height <= 0 {
+		return Settings{}, errors.Errorf("%s: the image size %gx%g is not positive", path, s.Width, s.Height)
+	}
+	if s.Max <= s.Min {
+		return Settings{}, errors.Errorf("%s: the view from %g to %g is empty", path, s.Min, s.Max)
+	}
+	if s.Palette, err = parsePalette(car.Palette); err != nil {
+		return Settings{}, errors.Wrap(err, path)
+	}
+	if _, err := kernelFor(&s); err != nil {
+		return Settings{}, errors.Wrap(err, path)
+	}
+	return s, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSidecar saves the sidecar of a zoomed, recolored view and checks
+// that loading it back gives the same settings and the same frame.
+func TestSidecar(t *testing.T) {
+	settings := testSettings(64, 48)
+	settings.SetView(complex(-0.743643, 0.131825), 200)
+	settings.MaxIterations = 400
+	settings.Palette = "gold"
+	settings.SmoothColoring = true
+	settings.Rotation = 30
+	settings.ColorOffset = 0.25
+	path := filepath.Join(t.TempDir(), "view.png")
+	if err := writeSidecar(path, &settings); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(sidecarPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var car sidecar
+	if err := json.Unmarshal(data, &car); err != nil {
+		t.Fatal(err)
+	}
+	if car.Version != version || car.Palette != "gold" {
+		t.Errorf("the sidecar records version %q and palette %q", car.Version, car.Palette)
+	}
+
+	loaded, err := readSidecar(sidecarPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, settings) {
+		t.Errorf("loaded %+v\nsaved  %+v", loaded, settings)
+	}
+	want, err := renderImage(&settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderImage(&loaded, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, got.Image.Pix, want.Image.Pix)
+}
+
+// TestSidecarDefaultPalette checks that a view on the default palette
+// records it by name.
+func TestSidecarDefaultPalette(t *testing.T) {
+	settings := testSettings(16, 16)
+	path := filepath.Join(t.TempDir(), "view.png")
+	if err := writeSidecar(path, &settings); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readSidecar(sidecarPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Palette != palettes[0].Name {
+		t.Errorf("loaded palette %q; want %q", loaded.Palette, palettes[0].Name)
+	}
+}
+
+// TestReadSidecarErrors checks that bad sidecars are rejected with the
+// exit status -load reports them with.
+func TestReadSidecarErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, body, want string
+		code             int
+	}{
+		{name: "syntax", body: `{"settings": {`, want: "could not parse", code: exitUsage},
+		{name: "size", body: `{"palette": "classic", "settings": {"Min": -2, "Max": 2}}`, want: "image size", code: exitUsage},
+		{name: "view", body: `{"palette": "classic", "settings": {"Width": 8, "Height": 8, "Min": 1, "Max": 1}}`, want: "is empty", code: exitUsage},
+		{name: "palette", body: `{"palette": "plaid", "settings": {"Width": 8, "Height": 8, "Min": -2, "Max": 2}}`, want: "unknown palette", code: exitUsage},
+		{name: "kernel", body: `{"palette": "gold", "settings": {"Width": 8, "Height": 8, "Min": -2, "Max": 2, "Kernel": "nope"}}`, want: "nope", code: exitUsage},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".png.json")
+		if err := os.WriteFile(path, []byte(tt.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readSidecar(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v; want an error mentioning %q", tt.name, err, tt.want)
+			continue
+		}
+		if code := exitCodeFor(err); code != tt.code {
+			t.Errorf("%s: exit status %d; want %d", tt.name, code, tt.code)
+		}
+	}
+
+	_, err := readSidecar(filepath.Join(dir, "missing.png.json"))
+	if err == nil || exitCodeFor(err) != exitIO {
+		t.Errorf("a missing sidecar gave %v; want an IO error", err)
+	}
+}
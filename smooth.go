@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// smoothIterations is the normalized iteration count of an orbit that
+// escaped to z after iters iterations, nu = iters + 1 - log(log|z|)/log 2,
+// which varies continuously across the edges of the bands. Orbits are
+// followed out to smoothBailout for it, so nu lies within a band's width
+// of iters+1-log2(log smoothBailout); a z too close to the origin to tell
+// gives iters itself.
+func smoothIterations(iters int64, z complex128) float64 {
+	n := float64(iters)
+	modulus := cmplx.Abs(z)
+	if modulus <= 1 {
+		return n
+	}
+	return n + 1 - math.Log(math.Log(modulus))/math.Ln2
+}
+
+// samplePixelSmooth is samplePixel with the normalized iteration count
+// too; orbits that don't escape get their plain count.
+func samplePixelSmooth(kernel Kernel, px, py float64, settings *Settings) (int64, float64) {
+	x, y := settings.PixelToComplex(px+settings.JitterX, py+settings.JitterY)
+	n, z, escaped := kernel.Iterate(seed(x, y, settings))
+	if !escaped {
+		return int64(n), float64(n)
+	}
+	return int64(n), smoothIterations(int64(n), z)
+}
+
+// smoothBlendSteps is how many key presses take Settings.SmoothBlend from
+// 0 to 1.
+const smoothBlendSteps = 10
+
+// smoothColor colors a pixel by its normalized iteration count nu, blended
+// with the whole count iters by Settings.SmoothBlend. Overrides and the
+// interior still go by iters.
+func smoothColor(iters int64, nu float64, settings *Settings) (float64, float64, float64) {
+	if _, ok := settings.ColorOverrides[int(iters)]; ok || iters == settings.MaxIterations {
+		return colorFor(iters, settings)
+	}
+	// orbits from far outside the set escape in a couple of iterations,
+	// for a nu just below 0
+	n := float64(iters)
+	return colorForCount(n+settings.SmoothBlend*(math.Max(nu, 0)-n), settings)
+}
+
+// pixelColor colors a pixel that took iters iterations, nu normalized,
+// from the color table, or by nu with Settings.SmoothColoring. Without a
+// table the color is computed directly.
+func pixelColor(colors *colorTable, iters int64, nu float64, settings *Settings) (float64, float64, float64) {
+	if settings.SmoothColoring {
+		return smoothColor(iters, nu, settings)
+	}
+	if colors == nil {
+		return colorFor(iters, settings)
+	}
+	return colors.Color(iters)
+}
+
+// sampleColor samples the pixel position (px, py) and colors it, for the
+// renderers that color each sample rather than each pixel.
+func sampleColor(kernel Kernel, px, py float64, settings *Settings) (float64, float64, float64) {
+	if settings.SmoothColoring {
+		iters, nu := samplePixelSmooth(kernel, px, py, settings)
+		return smoothColor(iters, nu, settings)
+	}
+	return colorFor(samplePixel(kernel, px, py, settings), settings)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// TestSmoothIterationsAlongRay walks outwards from the set along rays, on
+// which the normalized count must fall steadily, without the jumps of the
+// whole count, and stay within a band of where the bailout puts it.
+func TestSmoothIterationsAlongRay(t *testing.T) {
+	settings := testSettings(100, 100)
+	settings.SmoothColoring = true
+	rays := []struct {
+		name     string
+		from, to complex128
+	}{
+		{name: "real axis past the cusp", from: 0.3, to: 2},
+		{name: "diagonal far from the set", from: 2 + 2i, to: 20 + 20i},
+	}
+
+	for _, kernelName := range []string{"mandelbrot", "fixed"} {
+		settings.Kernel = kernelName
+		kernel, err := kernelFor(&settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ray := range rays {
+			const steps = 10000
+			last := math.Inf(1)
+			for s := 0; s <= steps; s++ {
+				c := ray.from + (ray.to-ray.from)*complex(float64(s)/steps, 0)
+				n, z, escaped := kernel.Iterate(c, c)
+				if !escaped {
+					t.Fatalf("%s: %s: %v didn't escape", kernelName, ray.name, c)
+				}
+				nu := smoothIterations(int64(n), z)
+
+				// smoothBailout < |z| <= smoothBailout^2 + |c|
+				low := float64(n) + 1 - math.Log2(math.Log(smoothBailout*smoothBailout+cmplx.Abs(c)))
+				high := float64(n) + 1 - math.Log2(math.Log(smoothBailout))
+				if nu < low || nu >= high {
+					t.Errorf("%s: %s: nu = %v for %v after %d iterations; want it in [%v, %v)",
+						kernelName, ray.name, nu, c, n, low, high)
+				}
+				if nu > last {
+					t.Errorf("%s: %s: nu rose from %v to %v at %v", kernelName, ray.name, last, nu, c)
+				}
+				if last-nu > 0.1 && s > 0 {
+					t.Errorf("%s: %s: nu jumped from %v to %v at %v", kernelName, ray.name, last, nu, c)
+				}
+				last = nu
+			}
+		}
+	}
+}
+
+// TestSmoothBlend checks that a blend of 0 gives the banded colors and 1
+// the fully smooth ones, with a blend between them in between, and that
+// the keys step it within [0, 1].
+func TestSmoothBlend(t *testing.T) {
+	settings := testSettings(100, 100)
+	settings.SmoothColoring = true
+	const iters, nu = 40, 40.6
+	color := func(blend float64) [3]float64 {
+		settings.SmoothBlend = blend
+		r, g, b := pixelColor(nil, iters, nu, &settings)
+		return [3]float64{r, g, b}
+	}
+	r, g, b := colorFor(iters, &settings)
+	if got, want := color(0), [3]float64{r, g, b}; got != want {
+		t.Errorf("a blend of 0 colors %v; want the banded %v", got, want)
+	}
+	r, g, b = colorForCount(nu, &settings)
+	if got, want := color(1), [3]float64{r, g, b}; got != want {
+		t.Errorf("a blend of 1 colors %v; want the smooth %v", got, want)
+	}
+	r, g, b = colorForCount(iters+0.5*(nu-iters), &settings)
+	if got, want := color(0.5), [3]float64{r, g, b}; got != want {
+		t.Errorf("a blend of 0.5 colors %v; want halfway's %v", got, want)
+	}
+	settings.SmoothColoring = false
+	r, g, b = colorFor(iters, &settings)
+	if got, want := color(1), [3]float64{r, g, b}; got != want {
+		t.Errorf("the blend changed a pixel without smooth coloring: %v; want %v", got, want)
+	}
+
+	settings.SmoothBlend = 1
+	if settings.AdjustSmoothBlend(1) {
+		t.Error("the blend went past 1")
+	}
+	for i := 0; i < 10; i++ {
+		if !settings.AdjustSmoothBlend(-1) {
+			t.Fatalf("the blend stuck at %v", settings.SmoothBlend)
+		}
+	}
+	if settings.SmoothBlend != 0 || settings.AdjustSmoothBlend(-1) {
+		t.Errorf("ten steps down from 1 reached %v; want to stop at 0", settings.SmoothBlend)
+	}
+	settings.AdjustSmoothBlend(1)
+	settings.AdjustSmoothBlend(1)
+	settings.AdjustSmoothBlend(1)
+	if settings.SmoothBlend != 0.3 {
+		t.Errorf("three steps up from 0 reached %v; want exactly 0.3", settings.SmoothBlend)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "math"
+
+// symmetryTolerance is how far off the real axis, in pixels, a view's
+// middle may sit and still be rendered as symmetric.
+const symmetryTolerance = 1e-6
+
+// symmetricView reports whether the lower half of the image can be
+// mirrored from the upper half: the kernel's set is symmetric about the
+// real axis and the view is centred on it, so rows py and Height-py sample
+// complex conjugates.
+func symmetricView(settings *Settings) bool {
+	switch kernelName(settings) {
+	case "mandelbrot", "tricorn":
+	default:
+		return false
+	}
+	if settings.JitterY != 0 || settings.Rotation != 0 || settings.Height < 2 {
+		return false
+	}
+	// a Julia set is only symmetric about the real axis for a real
+	// constant
+	if settings.Fractal == FractalJulia && settings.Julia.Y != 0 {
+		return false
+	}
+	pixel := (settings.Max - settings.Min) * settings.imageAspect() / settings.Height
+	return math.Abs(settings.Min+settings.Max-2*settings.Center.Y) <= pixel*symmetryTolerance
+}
+
+// mirroredRow reports whether row py of a symmetric view is filled in from
+// its partner in the upper half rather than rendered itself, which it is
+// when the region of interest takes in both rows.
+func mirroredRow(py float64, settings *Settings) bool {
+	m, ok := mirrorRow(py, settings.Height)
+	if !ok || py <= settings.Height/2 {
+		return false
+	}
+	b := settings.renderBounds()
+	in := func(y float64) bool { return int(y) >= b.Min.Y && int(y) < b.Max.Y }
+	return in(py) && in(m)
+}
+
+// mirrorRow returns the row mirroring row py and whether it is another row
+// of the image. Row 0 and the row on the axis have no partner.
+func mirrorRow(py, height float64) (float64, bool) {
+	m := height - py
+	return m, py > 0 && m != py && m < height
+}
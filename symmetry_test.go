@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestMirroredRender renders views symmetric about the real axis, which
+// fill the lower half from the upper, and checks every pixel against
+// iterating it on its own, both headless and in the window.
+func TestMirroredRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *Settings)
+	}{
+		{name: "mandelbrot", setup: func(s *Settings) {}},
+		{name: "tricorn", setup: func(s *Settings) { s.Kernel = "tricorn"; s.ApplyView(tricornView) }},
+		{name: "basilica", setup: func(s *Settings) {
+			s.Fractal = FractalJulia
+			s.Julia = Point{X: -1, Y: 0}
+			s.ApplyView(juliaView)
+		}},
+		{name: "odd height", setup: func(s *Settings) { s.Height = 47 }},
+	}
+	for _, tt := range tests {
+		settings := testSettings(64, 64)
+		tt.setup(&settings)
+		if !symmetricView(&settings) {
+			t.Fatalf("%s: the view isn't symmetric", tt.name)
+		}
+		kernel, err := kernelFor(&settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := renderImage(&settings, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mi := startImage(&settings)
+		mi.ForceRender()
+		finish(mi)
+
+		width, height := int(settings.Width), int(settings.Height)
+		mismatched := 0
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				want := samplePixel(kernel, float64(x), float64(y), &settings)
+				if r.Iterations[y*width+x] != want || mi.Iterations[y*width+x] != want {
+					mismatched++
+					t.Logf("%s: (%d, %d) took %d iterations headless and %d in the window; want %d",
+						tt.name, x, y, r.Iterations[y*width+x], mi.Iterations[y*width+x], want)
+				}
+			}
+		}
+		if mismatched > 0 {
+			t.Errorf("%s: %d pixels differ from a full render", tt.name, mismatched)
+		}
+		samePixels(t, mi.Snapshot().Pix, r.Image.Pix)
+	}
+}
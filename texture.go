@@ -0,0 +1,63 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// maxUploadFailures is how many uploads in a row may fail before the
+// texture is recreated.
+const maxUploadFailures = 3
+
+// frameTexture is the texture the image is uploaded to each frame. An
+// upload that fails leaves the last good frame showing, and repeated
+// failures recreate the texture in case the driver lost it.
+type frameTexture struct {
+	renderer *sdl.Renderer
+	Texture  *sdl.Texture
+	width    int32
+	height   int32
+	failures int
+}
+
+func newFrameTexture(renderer *sdl.Renderer, width, height int32) (*frameTexture, error) {
+	t := &frameTexture{renderer: renderer, width: width, height: height}
+	return t, t.create()
+}
+
+func (t *frameTexture) create() error {
+	texture, err := t.renderer.CreateTexture(
+		sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, t.width, t.height)
+	if err != nil {
+		return err
+	}
+	t.Texture = texture
+	return nil
+}
+
+// Update uploads pixels, a frame in the layout of MandelbrotImage.Pixels.
+func (t *frameTexture) Update(pixels []byte) {
+	err := t.Texture.Update(nil, pixels, int(t.width)*4)
+	if err == nil {
+		t.failures = 0
+		return
+	}
+
+	t.failures++
+	log.WithError(err).WithField("failures", t.failures).Error("could not upload the frame to the texture")
+	if t.failures < maxUploadFailures {
+		return
+	}
+
+	log.Warn("recreating the texture after repeated upload failures")
+	t.Texture.Destroy()
+	if err := t.create(); err != nil {
+		log.WithError(err).Error("could not recreate the texture")
+		return
+	}
+	t.failures = 0
+}
+
+func (t *frameTexture) Destroy() {
+	t.Texture.Destroy()
+}
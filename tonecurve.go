@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// toneCurvePoints is how many control points the tone curve has, evenly
+// spaced over the input range from black to white.
+const toneCurvePoints = 5
+
+// toneCurveStep is how far one panel adjustment moves a control point.
+const toneCurveStep = 0.05
+
+// toneCurve is an editable contrast curve applied to each color channel.
+// Entry i is how far the output at input i/(toneCurvePoints-1) is lifted
+// above the identity, so the zero curve leaves colors alone. The points are
+// joined by a monotone cubic spline, so a curve that never falls doesn't
+// overshoot between them.
+type toneCurve [toneCurvePoints]float64
+
+// Level is the output at control point i.
+func (c toneCurve) Level(i int) float64 {
+	return float64(i)/(toneCurvePoints-1) + c[i]
+}
+
+// SetLevel moves control point i to output v, clamped between its
+// neighbours so the curve stays monotonic, and reports whether it moved.
+func (c *toneCurve) SetLevel(i int, v float64) bool {
+	low, high := 0.0, 1.0
+	if i > 0 {
+		low = c.Level(i - 1)
+	}
+	if i < toneCurvePoints-1 {
+		high = c.Level(i + 1)
+	}
+	v = math.Max(low, math.Min(v, high))
+
+	offset := v - float64(i)/(toneCurvePoints-1)
+	if math.Abs(offset-c[i]) < 1e-9 {
+		return false
+	}
+	c[i] = offset
+	return true
+}
+
+// Apply maps v in [0, 1] through the curve. It uses Fritsch-Carlson
+// tangents, which keep the spline monotonic wherever the points are.
+func (c toneCurve) Apply(v float64) float64 {
+	if c == (toneCurve{}) {
+		return v
+	}
+
+	const h = 1.0 / (toneCurvePoints - 1)
+	var y, m [toneCurvePoints]float64
+	var delta [toneCurvePoints - 1]float64
+	for i := range y {
+		y[i] = c.Level(i)
+	}
+	for i := range delta {
+		delta[i] = (y[i+1] - y[i]) / h
+	}
+	m[0], m[toneCurvePoints-1] = delta[0], delta[toneCurvePoints-2]
+	for i := 1; i < toneCurvePoints-1; i++ {
+		if delta[i-1]*delta[i] > 0 {
+			m[i] = (delta[i-1] + delta[i]) / 2
+		}
+	}
+	for i, d := range delta {
+		if d == 0 {
+			m[i], m[i+1] = 0, 0
+			continue
+		}
+		a, b := m[i]/d, m[i+1]/d
+		if s := a*a + b*b; s > 9 {
+			tau := 3 / math.Sqrt(s)
+			m[i], m[i+1] = tau*a*d, tau*b*d
+		}
+	}
+
+	v = math.Max(0, math.Min(v, 1))
+	i := int(v / h)
+	if i >= toneCurvePoints-1 {
+		return y[toneCurvePoints-1]
+	}
+	t := (v - float64(i)*h) / h
+	t2, t3 := t*t, t*t*t
+	return (2*t3-3*t2+1)*y[i] + (t3-2*t2+t)*h*m[i] + (-2*t3+3*t2)*y[i+1] + (t3-t2)*h*m[i+1]
+}
+
+// String lists the output levels, or is empty for the identity curve.
+func (c toneCurve) String() string {
+	if c == (toneCurve{}) {
+		return ""
+	}
+	levels := make([]string, toneCurvePoints)
+	for i := range levels {
+		levels[i] = strconv.FormatFloat(c.Level(i), 'g', 4, 64)
+	}
+	return strings.Join(levels, ",")
+}
+
+// parseToneCurve parses the output levels of all toneCurvePoints control
+// points, as written by toneCurve.String. They must not decrease.
+func parseToneCurve(s string) (toneCurve, error) {
+	var c toneCurve
+	if s == "" {
+		return c, nil
+	}
+	fields := strings.Split(s, ",")
+	if len(fields) != toneCurvePoints {
+		return c, errors.Errorf("tone curve %q needs %d levels", s, toneCurvePoints)
+	}
+
+	previous := 0.0
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return c, errors.Wrapf(err, "tone curve level %d", i)
+		}
+		if v < previous || v > 1 {
+			return c, errors.Errorf("tone curve level %d is %g; levels must rise from 0 to 1", i, v)
+		}
+		previous = v
+		c[i] = v - float64(i)/(toneCurvePoints-1)
+	}
+	return c, nil
+}
+
+// toneCurveParams are the panel rows that edit the tone curve, one per
+// control point.
+func toneCurveParams() []panelParam {
+	params := make([]panelParam, toneCurvePoints)
+	for i := range params {
+		i := i
+		params[i] = panelParam{
+			Name: fmt.Sprintf("Curve at %d%%", i*100/(toneCurvePoints-1)),
+			Value: func(s *Settings) string {
+				return fmt.Sprintf("%.0f%%", s.ToneCurve.Level(i)*100)
+			},
+			Adjust: func(s *Settings, dir int) bool {
+				return s.ToneCurve.SetLevel(i, s.ToneCurve.Level(i)+toneCurveStep*float64(dir))
+			},
+			RecolorOnly: true,
+		}
+	}
+	return params
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// precisionMargin is how many float64 steps a pixel must span before the
+// view is still considered resolvable.
+const precisionMargin = 8
+
+// View frames a region of the complex plane in the same terms as Settings:
+// pixels map linearly onto [Min, Max] on both axes and Center is then
+// subtracted.
+type View struct {
+	Min    float64
+	Max    float64
+	Center Point
+}
+
+// homeView frames the whole set, real [-2.5, 1] by imaginary [-1.75, 1.75],
+// which keeps a square image at a 1:1 aspect.
+var homeView = View{
+	Min:    -1.75,
+	Max:    1.75,
+	Center: Point{X: 0.75, Y: 0.0},
+}
+
+// tricornView frames the Tricorn, real [-2.25, 1.75] by imaginary [-2, 2].
+var tricornView = View{
+	Min:    -2.0,
+	Max:    2.0,
+	Center: Point{X: 0.25, Y: 0.0},
+}
+
+// legacyView is the original, off-centre startup view.
+var legacyView = View{
+	Min:    -2.84,
+	Max:    2.0,
+	Center: Point{X: 0.5, Y: 0.0},
+}
+
+func (s *Settings) ApplyView(v View) {
+	s.Min = v.Min
+	s.Max = v.Max
+	s.Center = v.Center
+}
+
+// pixelAspect is Settings.PixelAspect with 0 meaning square pixels.
+func (s *Settings) pixelAspect() float64 {
+	if s.PixelAspect <= 0 {
+		return 1
+	}
+	return s.PixelAspect
+}
+
+// imageAspect is the image's height over its width, with an image of no
+// size taken as square.
+func (s *Settings) imageAspect() float64 {
+	if s.Width <= 0 || s.Height <= 0 {
+		return 1
+	}
+	return s.Height / s.Width
+}
+
+// pixelBlock is Settings.PixelBlock with 0 meaning every pixel.
+func (s *Settings) pixelBlock() int64 {
+	if s.PixelBlock < 1 {
+		return 1
+	}
+	return s.PixelBlock
+}
+
+// PixelToComplex maps an image pixel to its point on the complex plane.
+// Min to Max spans the width of the image, and the imaginary axis is
+// scaled about the middle by the image's aspect so pixels stay square on
+// the plane. With a PixelAspect other than 1 the real axis is stretched about the
+// middle of the image to match, and the whole grid is then turned by
+// Rotation about the middle of the view.
+func (s *Settings) PixelToComplex(px, py float64) (float64, float64) {
+	mid := (s.Min + s.Max) / 2
+	x := mid + (mapToRange(px, 0, s.Width, s.Min, s.Max)-mid)*s.pixelAspect()
+	y := mid + (mapToRange(py, 0, s.Height, s.Min, s.Max)-mid)*s.imageAspect()
+
+	x, y = rotate(x-mid, y-mid, s.Rotation)
+	return mid + x - s.Center.X, mid + y - s.Center.Y
+}
+
+// rotate turns (x, y) by angle radians about the origin.
+func rotate(x, y, angle float64) (float64, float64) {
+	if angle == 0 {
+		return x, y
+	}
+	sin, cos := math.Sincos(angle)
+	return x*cos - y*sin, x*sin + y*cos
+}
+
+// Rotate turns the view by angle radians, keeping Rotation in [-pi, pi].
+func (s *Settings) Rotate(angle float64) {
+	s.Rotation = math.Remainder(s.Rotation+angle, 2*math.Pi)
+}
+
+// Magnification is how far the view is zoomed relative to the startup
+// view, InitialSpan over the current span; 1 before InitialSpan is set.
+func (s *Settings) Magnification() float64 {
+	span := s.Max - s.Min
+	if s.InitialSpan <= 0 || span <= 0 {
+		return 1
+	}
+	return s.InitialSpan / span
+}
+
+// SetView frames the view on center at zoom times magnification, the
+// inverse of Magnification; Min, Max and Center follow from them. The span
+// is across the image's width, and PixelToComplex derives the vertical
+// span from the aspect, so a wide image shows less of the imaginary axis
+// rather than a squashed view. Without an InitialSpan zoom is relative to
+// the home view.
+func (s *Settings) SetView(center complex128, zoom float64) {
+	base := s.InitialSpan
+	if base <= 0 {
+		base = homeView.Max - homeView.Min
+	}
+	s.CenterOn(real(center), imag(center), base/zoom)
+}
+
+// formatMagnification renders a magnification compactly, e.g. "12.5x" or
+// "1.3e6x".
+func formatMagnification(m float64) string {
+	// anything that rounds up to 1000 at three digits goes in exponent
+	// form too
+	if m < 999.5 {
+		return strconv.FormatFloat(m, 'g', 3, 64) + "x"
+	}
+	text := strconv.FormatFloat(m, 'e', 1, 64)
+	e := strings.Index(text, "e")
+	exp, _ := strconv.Atoi(text[e+1:])
+	return text[:e] + "e" + strconv.Itoa(exp) + "x"
+}
+
+// rotationStep is how far the ; and ' keys turn the view.
+const rotationStep = math.Pi / 24
+
+// wheelZoomFactor scales the span with each tick of the mouse wheel
+// towards the user; ticks away scale it by the inverse.
+const wheelZoomFactor = 0.8
+
+// zoomFactors are the steps the zoom factor moves through, fine to coarse.
+var zoomFactors = []float64{1.1, 1.25, 1.5, 2, 4}
+
+// ViewCenter is the point of the complex plane in the middle of the image.
+func (s *Settings) ViewCenter() (float64, float64) {
+	return s.PixelToComplex(s.Width/2, s.Height/2)
+}
+
+// Zoom scales the view about its middle by ZoomFactor, in when in is set
+// and out otherwise. It reports whether the span changed, which it doesn't
+// once zooming out reaches MaxSpan.
+func (s *Settings) Zoom(in bool) bool {
+	factor := s.ZoomFactor
+	if in {
+		factor = 1 / factor
+	}
+
+	span := s.Max - s.Min
+	re, im := s.ViewCenter()
+	s.ZoomTo(re, im, factor)
+	return s.Max-s.Min != span
+}
+
+// ZoomAt scales the span by factor about the image position (px, py),
+// keeping the point under it fixed, and reports whether the span changed.
+// It won't zoom in past where float64 can tell pixels apart, so the span
+// never collapses.
+func (s *Settings) ZoomAt(px, py, factor float64) bool {
+	if factor <= 0 {
+		return false
+	}
+	span := s.Max - s.Min
+	next := span * factor
+	if s.MaxSpan > 0 && next > s.MaxSpan {
+		next = s.MaxSpan
+	}
+
+	re, im := s.PixelToComplex(px, py)
+	cre, cim := s.ViewCenter()
+	scale := next / span
+	zoomed := s.Clone()
+	zoomed.CenterOn(re+(cre-re)*scale, im+(cim-im)*scale, next)
+	if factor < 1 && PrecisionExhausted(zoomed) {
+		return false
+	}
+	*s = zoomed
+	return s.Max-s.Min != span
+}
+
+// StepZoomFactor moves ZoomFactor to the next finer (dir < 0) or coarser
+// (dir > 0) preset and reports whether it changed.
+func (s *Settings) StepZoomFactor(dir int) bool {
+	current := 0
+	for i, f := range zoomFactors {
+		if f <= s.ZoomFactor {
+			current = i
+		}
+	}
+
+	next := current + dir
+	if next < 0 || next >= len(zoomFactors) {
+		return false
+	}
+	s.ZoomFactor = zoomFactors[next]
+	return true
+}
+
+// PrecisionExhausted reports whether the view is zoomed in so far that
+// float64 can barely tell neighbouring pixels apart, which makes the image
+// blocky. The spacing between pixels is compared with the float64 epsilon
+// scaled to the largest coordinate magnitude used in the pixel mapping.
+func PrecisionExhausted(settings Settings) bool {
+	span := settings.Max - settings.Min
+	spacing := span / math.Max(settings.Width, settings.Height)
+	re, im := settings.ViewCenter()
+
+	magnitude := math.Max(math.Abs(settings.Min), math.Abs(settings.Max))
+	magnitude = math.Max(magnitude, math.Abs(re)+span)
+	magnitude = math.Max(magnitude, math.Abs(im)+span)
+
+	const epsilon = 0x1p-52
+	return spacing < precisionMargin*epsilon*magnitude
+}
+
+// ZoomTo moves the middle of the view to (re, im) and scales the span by
+// factor; a factor below 1 zooms in.
+func (s *Settings) ZoomTo(re, im, factor float64) {
+	s.CenterOn(re, im, (s.Max-s.Min)*factor)
+}
+
+// CenterOn frames the view on (re, im) with the given span across the
+// image, clamped to MaxSpan when that is set.
+func (s *Settings) CenterOn(re, im, span float64) {
+	if s.MaxSpan > 0 && span > s.MaxSpan {
+		span = s.MaxSpan
+	}
+	mid := (s.Min + s.Max) / 2
+
+	s.Min = mid - span/2
+	s.Max = mid + span/2
+	s.Center.X = mid - re
+	s.Center.Y = mid - im
+}
+
+// ComplexToPixel is the inverse of PixelToComplex.
+func (s *Settings) ComplexToPixel(re, im float64) (float64, float64) {
+	mid := (s.Min + s.Max) / 2
+	x, y := rotate(re+s.Center.X-mid, im+s.Center.Y-mid, -s.Rotation)
+	px := mapToRange(mid+x/s.pixelAspect(), s.Min, s.Max, 0, s.Width)
+	py := mapToRange(mid+y/s.imageAspect(), s.Min, s.Max, 0, s.Height)
+
+	return px, py
+}
+
+// ScreenToComplex maps the screen pixel (px, py) to the complex plane for
+// the given settings.
+func ScreenToComplex(px, py float64, settings Settings) (re, im float64) {
+	return settings.PixelToComplex(px, py)
+}
+
+// ComplexToScreen maps a point of the complex plane to its screen pixel
+// for the given settings; it is the inverse of ScreenToComplex.
+func ComplexToScreen(re, im float64, settings Settings) (px, py float64) {
+	return settings.ComplexToPixel(re, im)
+}
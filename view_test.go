@@ -0,0 +1,401 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestHomeViewContainsBulbs checks that the startup view frames the main
+// cardioid, the largest bulbs and the tip of the antenna, and that they
+// are in the set.
+func TestHomeViewContainsBulbs(t *testing.T) {
+	settings := testSettings(800, 800)
+	kernel, err := kernelFor(&settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bulbs := []struct {
+		name string
+		c    complex128
+	}{
+		{name: "main cardioid", c: 0},
+		{name: "cusp of the cardioid", c: 0.25},
+		{name: "period-2 bulb", c: -1},
+		{name: "upper period-3 bulb", c: -0.1226 + 0.7449i},
+		{name: "lower period-3 bulb", c: -0.1226 - 0.7449i},
+		{name: "period-4 bulb", c: -1.3107},
+		{name: "tip of the antenna", c: -2},
+	}
+	for _, b := range bulbs {
+		px, py := settings.ComplexToPixel(real(b.c), imag(b.c))
+		if px < 0 || px >= settings.Width || py < 0 || py >= settings.Height {
+			t.Errorf("%s at %v is outside the view, at pixel (%.1f, %.1f)", b.name, b.c, px, py)
+		}
+		if n, _, escaped := kernel.Iterate(b.c, b.c); escaped {
+			t.Errorf("%s at %v escaped after %d iterations", b.name, b.c, n)
+		}
+	}
+
+	// the whole set lies within real [-2, 0.5] by imaginary [-1.2, 1.2],
+	// which the view should take in with a margin
+	left, top := settings.PixelToComplex(0, 0)
+	right, bottom := settings.PixelToComplex(settings.Width, settings.Height)
+	if left > -2.25 || right < 0.75 || top > -1.45 || bottom < 1.45 {
+		t.Errorf("the view spans real [%v, %v] by imaginary [%v, %v]; want the whole set with a margin",
+			left, right, top, bottom)
+	}
+}
+
+// TestPrecisionExhausted zooms in on the seahorse valley, checking that the
+// warning stays off while every column of the image still has coordinates
+// of its own, and comes on once a pixel is down to a few float64 steps.
+func TestPrecisionExhausted(t *testing.T) {
+	settings := testSettings(800, 800)
+	if PrecisionExhausted(settings) {
+		t.Error("the home view is past float64 precision")
+	}
+
+	const re, im = -0.743643887037151, 0.13182590420533
+	for _, span := range []float64{1, 1e-5, 1e-9, 1e-11} {
+		settings.CenterOn(re, im, span)
+		if PrecisionExhausted(settings) {
+			t.Errorf("a span of %g is past float64 precision", span)
+		}
+		previous, _ := settings.PixelToComplex(0, 400)
+		for px := 1.0; px < settings.Width; px++ {
+			x, _ := settings.PixelToComplex(px, 400)
+			if x <= previous {
+				t.Fatalf("a span of %g: column %v isn't right of the one before", span, px)
+			}
+			previous = x
+		}
+	}
+	for _, span := range []float64{1e-13, 1e-15} {
+		settings.CenterOn(re, im, span)
+		if !PrecisionExhausted(settings) {
+			t.Errorf("a span of %g, %g a pixel, is within float64 precision", span, span/settings.Width)
+		}
+	}
+}
+
+// TestZoomOutStopsAtMaxSpan zooms out until the span reaches MaxSpan,
+// after which Zoom reports no change and the view keeps its center.
+func TestZoomOutStopsAtMaxSpan(t *testing.T) {
+	settings := testSettings(800, 800)
+	re, im := settings.ViewCenter()
+	zooms := 0
+	for settings.Zoom(false) {
+		zooms++
+		if zooms > 100 {
+			t.Fatalf("still zooming out at a span of %v", settings.Max-settings.Min)
+		}
+	}
+	if span := settings.Max - settings.Min; span != settings.MaxSpan {
+		t.Errorf("zooming out stopped at a span of %v; want %v", span, settings.MaxSpan)
+	}
+	if gotRe, gotIm := settings.ViewCenter(); gotRe != re || gotIm != im {
+		t.Errorf("the center moved from %v%+vi to %v%+vi", re, im, gotRe, gotIm)
+	}
+	if !settings.Zoom(true) {
+		t.Error("couldn't zoom back in from MaxSpan")
+	}
+
+	settings.MaxSpan = 0
+	settings.CenterOn(re, im, 1000)
+	if span := settings.Max - settings.Min; span != 1000 {
+		t.Errorf("with no MaxSpan the span is %v; want 1000", span)
+	}
+}
+
+// TestAdjustIterationsClamps checks that MaxIterations never drops below
+// 1 or rises past IterationLimit, and that hitting either bound reports no
+// change.
+func TestAdjustIterationsClamps(t *testing.T) {
+	settings := testSettings(1, 1)
+	settings.MaxIterations = 10
+	if !settings.AdjustIterations(-25) || settings.MaxIterations != 1 {
+		t.Errorf("10 less 25 gave %d iterations; want 1", settings.MaxIterations)
+	}
+	if settings.AdjustIterations(-5) || settings.MaxIterations != 1 {
+		t.Errorf("lowering from 1 changed it to %d", settings.MaxIterations)
+	}
+	if !settings.AdjustIterations(settings.IterationLimit*2) || settings.MaxIterations != settings.IterationLimit {
+		t.Errorf("raising past the limit gave %d; want %d", settings.MaxIterations, settings.IterationLimit)
+	}
+	if settings.AdjustIterations(1) {
+		t.Error("raising from the limit reported a change")
+	}
+
+	settings.IterationLimit = 0
+	if !settings.AdjustIterations(1) || settings.MaxIterations != 100001 {
+		t.Errorf("with no limit raising gave %d", settings.MaxIterations)
+	}
+}
+
+// TestPixelAspect checks that with pixels twice as wide as they are tall
+// a pixel covers twice as much of the real axis as of the imaginary one,
+// stretched about the middle of the view, and that ComplexToPixel undoes
+// it.
+func TestPixelAspect(t *testing.T) {
+	square := testSettings(400, 400)
+	wide := testSettings(400, 400)
+	wide.PixelAspect = 2
+
+	midRe, midIm := square.PixelToComplex(200, 200)
+	if re, im := wide.PixelToComplex(200, 200); re != midRe || im != midIm {
+		t.Errorf("the middle moved from %v%+vi to %v%+vi", midRe, midIm, re, im)
+	}
+	for _, corner := range [][2]float64{{0, 0}, {0, 400}, {400, 0}, {400, 400}} {
+		re, im := square.PixelToComplex(corner[0], corner[1])
+		wantRe, wantIm := midRe+(re-midRe)*2, im
+		gotRe, gotIm := wide.PixelToComplex(corner[0], corner[1])
+		if math.Abs(gotRe-wantRe) > 1e-12 || math.Abs(gotIm-wantIm) > 1e-12 {
+			t.Errorf("corner %v is at %v%+vi; want %v%+vi", corner, gotRe, gotIm, wantRe, wantIm)
+		}
+	}
+
+	x0, y0 := wide.PixelToComplex(50, 50)
+	x1, _ := wide.PixelToComplex(51, 50)
+	_, y1 := wide.PixelToComplex(50, 51)
+	if d := (x1 - x0) / (y1 - y0); math.Abs(d-2) > 1e-9 {
+		t.Errorf("a pixel is %v times as wide as it is tall on the plane; want 2", d)
+	}
+	if px, py := wide.ComplexToPixel(x0, y0); math.Abs(px-50) > 1e-9 || math.Abs(py-50) > 1e-9 {
+		t.Errorf("ComplexToPixel took (50, 50) back to (%v, %v)", px, py)
+	}
+}
+
+// TestMagnification zooms in from the startup view, checking the
+// magnification against the span and how it is shown.
+func TestMagnification(t *testing.T) {
+	settings := testSettings(800, 800)
+	if m := settings.Magnification(); m != 1 {
+		t.Errorf("the startup view is magnified %v times", m)
+	}
+	settings.Zoom(true)
+	if m := settings.Magnification(); math.Abs(m-settings.ZoomFactor) > 1e-9 {
+		t.Errorf("one zoom in magnifies %v times; want %v", m, settings.ZoomFactor)
+	}
+	re, im := settings.ViewCenter()
+	settings.CenterOn(re, im, settings.InitialSpan/1e6)
+	if m := settings.Magnification(); math.Abs(m-1e6) > 1e-3 {
+		t.Errorf("a millionth of the span magnifies %v times", m)
+	}
+
+	settings.InitialSpan = 0
+	if m := settings.Magnification(); m != 1 {
+		t.Errorf("with no InitialSpan the view is magnified %v times; want 1", m)
+	}
+
+	tests := []struct {
+		m    float64
+		want string
+	}{
+		{1, "1x"},
+		{1.25, "1.25x"},
+		{12.5, "12.5x"},
+		{999, "999x"},
+		{999.7, "1.0e3x"},
+		{1000, "1.0e3x"},
+		{1.3e6, "1.3e6x"},
+		{2.5e15, "2.5e15x"},
+	}
+	for _, tt := range tests {
+		if got := formatMagnification(tt.m); got != tt.want {
+			t.Errorf("formatMagnification(%v) is %q; want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+// roundTripSettings are views of different sizes, depths, shapes and
+// rotations to map pixels through.
+func roundTripSettings() []Settings {
+	var all []Settings
+	for _, size := range [][2]float64{{800, 800}, {640, 480}, {31, 97}} {
+		for _, view := range []View{
+			homeView,
+			legacyView,
+			{Min: -0.02, Max: 0.02, Center: Point{X: 0.745, Y: -0.11}},
+			{Min: 0.1318259 - 5e-9, Max: 0.1318259 + 5e-9, Center: Point{X: 0.875, Y: 0}},
+		} {
+			for _, aspect := range []float64{1, 2, 0.75} {
+				for _, rotation := range []float64{0, math.Pi / 6, -2.5} {
+					s := testSettings(size[0], size[1])
+					s.ApplyView(view)
+					s.PixelAspect = aspect
+					s.Rotation = rotation
+					all = append(all, s)
+				}
+			}
+		}
+	}
+	return all
+}
+
+// TestScreenComplexRoundTrip maps pixels to the plane and back, and points
+// of the plane to pixels and back, for every view in roundTripSettings.
+func TestScreenComplexRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, settings := range roundTripSettings() {
+		span := settings.Max - settings.Min
+		for i := 0; i < 200; i++ {
+			px, py := rng.Float64()*settings.Width, rng.Float64()*settings.Height
+			re, im := ScreenToComplex(px, py, settings)
+			gotX, gotY := ComplexToScreen(re, im, settings)
+			// at the deepest view a float64 step is a few millionths of a
+			// pixel
+			if math.Abs(gotX-px) > 1e-4 || math.Abs(gotY-py) > 1e-4 {
+				t.Fatalf("%vx%v, span %g, aspect %v, rotation %v: (%v, %v) came back as (%v, %v)",
+					settings.Width, settings.Height, span, settings.PixelAspect, settings.Rotation, px, py, gotX, gotY)
+			}
+
+			re, im = ScreenToComplex(rng.Float64()*settings.Width, rng.Float64()*settings.Height, settings)
+			x, y := ComplexToScreen(re, im, settings)
+			gotRe, gotIm := ScreenToComplex(x, y, settings)
+			if math.Abs(gotRe-re) > span*1e-6 || math.Abs(gotIm-im) > span*1e-6 {
+				t.Fatalf("%vx%v, span %g, aspect %v, rotation %v: %v%+vi came back as %v%+vi",
+					settings.Width, settings.Height, span, settings.PixelAspect, settings.Rotation, re, im, gotRe, gotIm)
+			}
+		}
+	}
+}
+
+// TestRotation turns the view a quarter turn, checking that the grid turns
+// about the middle of the view, that Rotate keeps the angle in [-pi, pi],
+// and that a rotated view isn't mirrored.
+func TestRotation(t *testing.T) {
+	settings := testSettings(400, 400)
+	midRe, midIm := settings.PixelToComplex(200, 200)
+	rightRe, rightIm := settings.PixelToComplex(300, 200)
+
+	settings.Rotate(math.Pi / 2)
+	if re, im := settings.PixelToComplex(200, 200); math.Abs(re-midRe) > 1e-12 || math.Abs(im-midIm) > 1e-12 {
+		t.Errorf("the middle moved from %v%+vi to %v%+vi", midRe, midIm, re, im)
+	}
+	// turned a quarter, the pixel right of the middle shows the point that
+	// was that far below it
+	re, im := settings.PixelToComplex(300, 200)
+	if math.Abs(re-midRe) > 1e-12 || math.Abs(im-(midIm+(rightRe-midRe))) > 1e-12 {
+		t.Errorf("a quarter turn put %v%+vi right of the middle; want %v%+vi", re, im, midRe, midIm+(rightRe-midRe))
+	}
+	if rightIm != midIm {
+		t.Fatalf("unrotated, the pixel right of the middle is off the axis at %v", rightIm)
+	}
+	if symmetricView(&settings) {
+		t.Error("the rotated view is mirrored")
+	}
+
+	for i := 0; i < 7; i++ {
+		settings.Rotate(math.Pi / 2)
+		if settings.Rotation < -math.Pi || settings.Rotation > math.Pi {
+			t.Fatalf("the rotation grew to %v", settings.Rotation)
+		}
+	}
+	if math.Abs(settings.Rotation) > 1e-12 {
+		t.Errorf("two whole turns left a rotation of %v", settings.Rotation)
+	}
+}
+
+// TestSetView frames views by center and zoom, checking them with
+// ViewCenter and Magnification, which SetView inverts, on square, wide and
+// tall images alike, and that the vertical span follows the image's aspect
+// so pixels stay square on the plane.
+func TestSetView(t *testing.T) {
+	for _, size := range [][2]float64{{800, 800}, {1280, 720}, {300, 600}} {
+		settings := testSettings(size[0], size[1])
+		for _, v := range []struct {
+			center complex128
+			zoom   float64
+		}{
+			{-0.75, 1},
+			{-0.7453 + 0.1127i, 350},
+			{-0.743643887037151 + 0.13182590420533i, 1e9},
+			{0.25, 0.75},
+		} {
+			settings.SetView(v.center, v.zoom)
+			re, im := settings.ViewCenter()
+			if math.Abs(re-real(v.center)) > 1e-12 || math.Abs(im-imag(v.center)) > 1e-12 {
+				t.Errorf("%vx%v: SetView(%v, %v) centered on %v%+vi", size[0], size[1], v.center, v.zoom, re, im)
+			}
+			if m := settings.Magnification(); math.Abs(m/v.zoom-1) > 1e-9 {
+				t.Errorf("%vx%v: SetView(%v, %v) magnified %v times", size[0], size[1], v.center, v.zoom, m)
+			}
+
+			span := settings.Max - settings.Min
+			left, top := settings.PixelToComplex(0, 0)
+			right, bottom := settings.PixelToComplex(size[0], size[1])
+			if math.Abs((right-left)/span-1) > 1e-6 {
+				t.Errorf("%vx%v: SetView(%v, %v) spans %v across; want %v", size[0], size[1], v.center, v.zoom, right-left, span)
+			}
+			if want := span * size[1] / size[0]; math.Abs((bottom-top)/want-1) > 1e-6 {
+				t.Errorf("%vx%v: SetView(%v, %v) spans %v down; want %v", size[0], size[1], v.center, v.zoom, bottom-top, want)
+			}
+			if px, py := settings.ComplexToPixel(right, bottom); math.Abs(px-size[0]) > 1e-3 || math.Abs(py-size[1]) > 1e-3 {
+				t.Errorf("%vx%v: ComplexToPixel took the far corner back to (%v, %v)", size[0], size[1], px, py)
+			}
+		}
+
+		// zooming out past MaxSpan stops there
+		settings.SetView(0, 0.01)
+		if span := settings.Max - settings.Min; span != settings.MaxSpan {
+			t.Errorf("%vx%v: a zoom of 0.01 gave a span of %v; want MaxSpan", size[0], size[1], span)
+		}
+	}
+
+	var settings Settings
+	settings.Width, settings.Height = 100, 100
+	settings.SetView(-0.5, 2)
+	if span := settings.Max - settings.Min; math.Abs(span-(homeView.Max-homeView.Min)/2) > 1e-12 {
+		t.Errorf("with no InitialSpan a zoom of 2 gave a span of %v; want half the home view's", span)
+	}
+}
+
+// TestZoomAt zooms about off-center pixels, checking that the point under
+// the cursor stays put with and without rotation, that zooming out stops
+// at MaxSpan and that zooming in stops at the precision limit.
+func TestZoomAt(t *testing.T) {
+	for _, rotation := range []float64{0, math.Pi / 5} {
+		settings := testSettings(640, 480)
+		settings.Rotation = rotation
+		for _, zoom := range []struct{ px, py, factor float64 }{
+			{100, 400, wheelZoomFactor},
+			{600, 20, wheelZoomFactor},
+			{320.5, 17.25, 1 / wheelZoomFactor},
+		} {
+			re, im := settings.PixelToComplex(zoom.px, zoom.py)
+			span := settings.Max - settings.Min
+			if !settings.ZoomAt(zoom.px, zoom.py, zoom.factor) {
+				t.Fatalf("rotation %v: zooming by %v at (%v, %v) changed nothing", rotation, zoom.factor, zoom.px, zoom.py)
+			}
+			if got := (settings.Max - settings.Min) / span; math.Abs(got-zoom.factor) > 1e-12 {
+				t.Errorf("rotation %v: zooming by %v scaled the span by %v", rotation, zoom.factor, got)
+			}
+			gotRe, gotIm := settings.PixelToComplex(zoom.px, zoom.py)
+			if math.Abs(gotRe-re) > span*1e-12 || math.Abs(gotIm-im) > span*1e-12 {
+				t.Errorf("rotation %v: the point under (%v, %v) moved from %v%+vi to %v%+vi",
+					rotation, zoom.px, zoom.py, re, im, gotRe, gotIm)
+			}
+		}
+	}
+
+	settings := testSettings(640, 480)
+	for i := 0; settings.ZoomAt(10, 10, 1/wheelZoomFactor); i++ {
+		if i > 100 {
+			t.Fatalf("still zooming out at a span of %v", settings.Max-settings.Min)
+		}
+	}
+	if span := settings.Max - settings.Min; span != settings.MaxSpan {
+		t.Errorf("zooming out stopped at a span of %v; want MaxSpan", span)
+	}
+
+	for i := 0; settings.ZoomAt(10, 10, wheelZoomFactor); i++ {
+		if i > 1000 {
+			t.Fatalf("still zooming in at a span of %v", settings.Max-settings.Min)
+		}
+	}
+	if span := settings.Max - settings.Min; PrecisionExhausted(settings) || span <= 0 {
+		t.Errorf("zooming in stopped at a span of %v, past float64 precision", span)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// viewStringScheme prefixes every view string.
+const viewStringScheme = "mandel:"
+
+// sharedView is a view in the form it is shared as a single line, e.g.
+// "mandel:c=-0.743643+0.131825i&zoom=1e6&iter=2000". Zoom is relative to
+// the kernel's home view rather than the startup view, so the string means
+// the same thing whatever view it is opened from.
+type sharedView struct {
+	Kernel     string
+	Formula    string
+	Center     complex128
+	Zoom       float64
+	Iterations int64
+	Rotation   float64
+	Palette    string
+	// Julia is the Julia constant in Julia mode, and nil otherwise.
+	Julia *Point
+}
+
+func homeSpan(k kernelRegistration) float64 {
+	return k.Home.Max - k.Home.Min
+}
+
+// sharedViewOf captures the current view of settings.
+func sharedViewOf(settings *Settings) (sharedView, error) {
+	k, err := registrationFor(settings)
+	if err != nil {
+		return sharedView{}, err
+	}
+	re, im := settings.ViewCenter()
+	var julia *Point
+	if settings.Fractal == FractalJulia {
+		julia = &Point{X: settings.Julia.X, Y: settings.Julia.Y}
+	}
+	return sharedView{
+		Kernel:     k.Name,
+		Formula:    settings.Formula,
+		Center:     complex(re, im),
+		Zoom:       homeSpan(k) / (settings.Max - settings.Min),
+		Iterations: settings.MaxIterations,
+		Rotation:   settings.Rotation,
+		Palette:    settings.Palette,
+		Julia:      julia,
+	}, nil
+}
+
+// String renders the view with every value at full precision, leaving out
+// the kernel and palette when they are the defaults and the rotation when
+// there is none.
+func (v sharedView) String() string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+	point := func(re, im float64) string {
+		text := format(im)
+		if !strings.HasPrefix(text, "-") {
+			text = "+" + text
+		}
+		return format(re) + text + "i"
+	}
+	fields := []string{
+		"c=" + point(real(v.Center), imag(v.Center)),
+		"zoom=" + format(v.Zoom),
+		"iter=" + strconv.FormatInt(v.Iterations, 10),
+	}
+	if v.Rotation != 0 {
+		fields = append(fields, "rot="+format(v.Rotation))
+	}
+	if v.Julia != nil {
+		fields = append(fields, "julia="+point(v.Julia.X, v.Julia.Y))
+	}
+	if v.Palette != "" && v.Palette != palettes[0].Name {
+		fields = append(fields, "pal="+v.Palette)
+	}
+	if v.Kernel != "" && v.Kernel != kernels[0].Name {
+		fields = append(fields, "kernel="+v.Kernel)
+	}
+	if v.Formula != "" {
+		fields = append(fields, "formula="+url.QueryEscape(v.Formula))
+	}
+	return viewStringScheme + strings.Join(fields, "&")
+}
+
+// ViewString describes the current view of settings as a view string.
+func ViewString(settings *Settings) (string, error) {
+	v, err := sharedViewOf(settings)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// ParseViewString parses a view string. c, zoom and iter are required;
+// rot, julia, pal, kernel and formula are optional, and anything else is
+// an error.
+func ParseViewString(s string) (sharedView, error) {
+	if !strings.HasPrefix(s, viewStringScheme) {
+		return sharedView{}, errors.Errorf("%q doesn't start with %q", s, viewStringScheme)
+	}
+
+	var v sharedView
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(strings.TrimPrefix(s, viewStringScheme), "&") {
+		eq := strings.Index(field, "=")
+		if eq < 0 {
+			return sharedView{}, errors.Errorf("%q is not key=value", field)
+		}
+		key, value := field[:eq], field[eq+1:]
+		if seen[key] {
+			return sharedView{}, errors.Errorf("%s is given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "c":
+			if !strings.HasSuffix(value, "i") {
+				return sharedView{}, errors.Errorf("the center %q is not re+imi", value)
+			}
+			v.Center, err = strconv.ParseComplex(value, 128)
+		case "zoom":
+			v.Zoom, err = strconv.ParseFloat(value, 64)
+			if err == nil && !(v.Zoom > 0 && !math.IsInf(v.Zoom, 0)) {
+				err = errors.New("the zoom must be positive")
+			}
+		case "iter":
+			v.Iterations, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && v.Iterations < 1 {
+				err = errors.New("there must be at least one iteration")
+			}
+		case "rot":
+			v.Rotation, err = strconv.ParseFloat(value, 64)
+		case "julia":
+			var k complex128
+			if !strings.HasSuffix(value, "i") {
+				return sharedView{}, errors.Errorf("the Julia constant %q is not re+imi", value)
+			}
+			k, err = strconv.ParseComplex(value, 128)
+			v.Julia = &Point{X: real(k), Y: imag(k)}
+		case "pal":
+			v.Palette, err = parsePalette(value)
+		case "kernel":
+			v.Kernel = value
+		case "formula":
+			v.Formula, err = url.QueryUnescape(value)
+		default:
+			return sharedView{}, errors.Errorf("unknown key %q; use c, zoom, iter, rot, julia, pal, kernel or formula", key)
+		}
+		if err != nil {
+			return sharedView{}, errors.Wrapf(err, "invalid %s", key)
+		}
+	}
+
+	for _, key := range []string{"c", "zoom", "iter"} {
+		if !seen[key] {
+			return sharedView{}, errors.Errorf("%q has no %s", s, key)
+		}
+	}
+	if math.IsNaN(real(v.Center)) || math.IsNaN(imag(v.Center)) || math.IsInf(real(v.Center), 0) || math.IsInf(imag(v.Center), 0) {
+		return sharedView{}, errors.Errorf("the center %v is not a finite point", v.Center)
+	}
+	if v.Formula != "" && v.Kernel == "" {
+		v.Kernel = "formula"
+	}
+	return v, nil
+}
+
+// Apply frames settings on the view. InitialSpan becomes the kernel's
+// home span, so the magnification shown is the string's zoom.
+func (v sharedView) Apply(settings *Settings) error {
+	next := *settings
+	next.Kernel = v.Kernel
+	next.Formula = v.Formula
+	k, err := registrationFor(&next)
+	if err != nil {
+		return err
+	}
+	if _, err := k.New(&next); err != nil {
+		return err
+	}
+
+	next.InitialSpan = homeSpan(k)
+	next.CenterOn(real(v.Center), imag(v.Center), homeSpan(k)/v.Zoom)
+	next.MaxIterations = v.Iterations
+	if next.IterationLimit > 0 && next.MaxIterations > next.IterationLimit {
+		next.MaxIterations = next.IterationLimit
+	}
+	next.Rotation = v.Rotation
+	next.Fractal = FractalMandelbrot
+	if v.Julia != nil {
+		next.Fractal = FractalJulia
+		next.Julia = *v.Julia
+	}
+	if v.Palette != "" {
+		next.Palette = v.Palette
+	}
+	*settings = next
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestViewStringRoundTrip shares views of each kind, checking that the
+// string parses back to the same view and applies to the same settings.
+func TestViewStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *Settings)
+	}{
+		{name: "home", setup: func(s *Settings) {}},
+		{name: "deep", setup: func(s *Settings) {
+			s.CenterOn(-0.743643887037151, 0.13182590420533, 1e-11)
+			s.MaxIterations = 5000
+		}},
+		{name: "rotated", setup: func(s *Settings) { s.Rotation = -math.Pi / 7 }},
+		{name: "julia", setup: func(s *Settings) {
+			s.Fractal = FractalJulia
+			s.Julia = Point{X: -0.8, Y: 0.156}
+			s.ApplyView(juliaView)
+		}},
+		{name: "palette and kernel", setup: func(s *Settings) {
+			s.Palette = "gold"
+			if err := switchKernel(s, "tricorn"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "formula", setup: func(s *Settings) {
+			s.Kernel = "formula"
+			s.Formula = "z^3 + c*(1+i)"
+		}},
+	}
+	for _, tt := range tests {
+		settings := testSettings(800, 800)
+		tt.setup(&settings)
+		text, err := ViewString(&settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(text, viewStringScheme) || strings.ContainsAny(text, " \n") {
+			t.Errorf("%s: %q isn't a single-word view string", tt.name, text)
+		}
+
+		v, err := ParseViewString(text)
+		if err != nil {
+			t.Fatalf("%s: %q: %v", tt.name, text, err)
+		}
+		want, err := sharedViewOf(&settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the kernel is left out for the default, and the formula implies
+		// its kernel
+		if want.Kernel == kernels[0].Name {
+			want.Kernel = ""
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("%s: %q parsed as %+v; want %+v", tt.name, text, v, want)
+		}
+
+		applied := testSettings(800, 800)
+		if err := v.Apply(&applied); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		again, err := ViewString(&applied)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != text {
+			t.Errorf("%s: applying %q gave %q", tt.name, text, again)
+		}
+		re, im := settings.ViewCenter()
+		gotRe, gotIm := applied.ViewCenter()
+		span := settings.Max - settings.Min
+		if math.Abs(gotRe-re) > span*1e-6 || math.Abs(gotIm-im) > span*1e-6 ||
+			math.Abs((applied.Max-applied.Min)/span-1) > 1e-9 {
+			t.Errorf("%s: applying %q framed %v%+vi at a span of %v; want %v%+vi at %v",
+				tt.name, text, gotRe, gotIm, applied.Max-applied.Min, re, im, span)
+		}
+	}
+}
+
+// TestParseViewStringErrors checks that malformed view strings are
+// rejected.
+func TestParseViewStringErrors(t *testing.T) {
+	for _, s := range []string{
+		"c=-0.75+0i&zoom=1&iter=200",
+		"mandel:zoom=1&iter=200",
+		"mandel:c=-0.75+0i&iter=200",
+		"mandel:c=-0.75+0i&zoom=1",
+		"mandel:c=-0.75&zoom=1&iter=200",
+		"mandel:c=-0.75+0i&zoom=0&iter=200",
+		"mandel:c=-0.75+0i&zoom=-2&iter=200",
+		"mandel:c=-0.75+0i&zoom=1&iter=0",
+		"mandel:c=-0.75+0i&zoom=1&iter=200&iter=300",
+		"mandel:c=-0.75+0i&zoom=1&iter=200&depth=3",
+		"mandel:c=-0.75+0i&zoom=1&iter=200&pal=plaid",
+		"mandel:c=NaN+0i&zoom=1&iter=200",
+		"mandel:c=-0.75+0i&zoom=1&iter=200&julia=0.3",
+		"mandel:c=-0.75+0i&zoom&iter=200",
+	} {
+		if v, err := ParseViewString(s); err == nil {
+			t.Errorf("%q parsed as %+v", s, v)
+		}
+	}
+
+	settings := testSettings(800, 800)
+	v, err := ParseViewString("mandel:c=0+0i&zoom=1&iter=200&kernel=nonesuch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := settings
+	if err := v.Apply(&settings); err == nil {
+		t.Error("a view of an unknown kernel applied")
+	}
+	if !reflect.DeepEqual(settings, before) {
+		t.Error("a view that failed to apply changed the settings")
+	}
+}